@@ -0,0 +1,167 @@
+package fov
+
+// Point is a tile coordinate on a map
+type Point struct {
+	X int
+	Y int
+}
+
+// Map is anything that can tell the size of its tile map and whether a tile blocks vision
+type Map interface {
+	Size() (width, height int)
+	IsOpaque(x, y int) bool
+}
+
+// Visibility is the result of a field of view computation
+type Visibility struct {
+	width   int
+	height  int
+	visible []bool
+}
+
+// Visible reports whether the tile could be seen from the origin
+func (v *Visibility) Visible(x, y int) bool {
+	if x < 0 || y < 0 || x >= v.width || y >= v.height {
+		return false
+	}
+	return v.visible[x*v.height+y]
+}
+
+// Points lists every visible tile
+func (v *Visibility) Points() []Point {
+	list := make([]Point, 0)
+	for x := 0; x < v.width; x++ {
+		for y := 0; y < v.height; y++ {
+			if v.visible[x*v.height+y] {
+				list = append(list, Point{x, y})
+			}
+		}
+	}
+	return list
+}
+
+func (v *Visibility) set(x, y int) {
+	if x >= 0 && y >= 0 && x < v.width && y < v.height {
+		v.visible[x*v.height+y] = true
+	}
+}
+
+// Multipliers transforming the first octant into all of the other ones
+var octants = [8][4]int{
+	{1, 0, 0, 1},
+	{0, 1, 1, 0},
+	{0, -1, 1, 0},
+	{-1, 0, 0, 1},
+	{-1, 0, 0, -1},
+	{0, -1, -1, 0},
+	{0, 1, -1, 0},
+	{1, 0, 0, -1},
+}
+
+// Compute runs recursive shadowcasting from the origin. Opaque tiles are visible themselves but hide what is behind them.
+func Compute(m Map, origin Point, radius int) *Visibility {
+	width, height := m.Size()
+	v := &Visibility{
+		width:   width,
+		height:  height,
+		visible: make([]bool, width*height),
+	}
+	v.set(origin.X, origin.Y)
+
+	for _, o := range octants {
+		castLight(m, v, origin, radius, 1, 1.0, 0.0, o[0], o[1], o[2], o[3])
+	}
+	return v
+}
+
+func castLight(m Map, v *Visibility, origin Point, radius, row int, start, end float64, xx, xy, yx, yy int) {
+	if start < end {
+		return
+	}
+	width, height := m.Size()
+	radiusSquared := radius * radius
+
+	for j := row; j <= radius; j++ {
+		dx, dy := -j-1, -j
+		blocked := false
+		newStart := start
+
+		for dx <= 0 {
+			dx++
+			x := origin.X + dx*xx + dy*xy
+			y := origin.Y + dx*yx + dy*yy
+
+			leftSlope := (float64(dx) - 0.5) / (float64(dy) + 0.5)
+			rightSlope := (float64(dx) + 0.5) / (float64(dy) - 0.5)
+			if start < rightSlope {
+				continue
+			}
+			if end > leftSlope {
+				break
+			}
+
+			if dx*dx+dy*dy <= radiusSquared {
+				v.set(x, y)
+			}
+
+			opaque := x < 0 || y < 0 || x >= width || y >= height || m.IsOpaque(x, y)
+			if blocked {
+				if opaque {
+					newStart = rightSlope
+					continue
+				}
+				blocked = false
+				start = newStart
+			} else if opaque && j < radius {
+				blocked = true
+				castLight(m, v, origin, radius, j+1, start, leftSlope, xx, xy, yx, yy)
+				newStart = rightSlope
+			}
+		}
+
+		if blocked {
+			break
+		}
+	}
+}
+
+// LineOfSight walks a bresenham line between two tiles and reports whether nothing opaque is in between.
+// The end points themselves are not checked.
+func LineOfSight(m Map, from, to Point) bool {
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	sx, sy := 1, 1
+	if from.X > to.X {
+		sx = -1
+	}
+	if from.Y > to.Y {
+		sy = -1
+	}
+
+	err := dx + dy
+	x, y := from.X, from.Y
+	for {
+		if x == to.X && y == to.Y {
+			return true
+		}
+		if (x != from.X || y != from.Y) && m.IsOpaque(x, y) {
+			return false
+		}
+
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var validPlayerName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// playerAccount is everything about a player that survives a restart
+type playerAccount struct {
+	Name       string            `json:"name"`
+	Inventory  *Inventory        `json:"inventory"`
+	Attributes *attributes       `json:"attributes"`
+	Equipment  map[string]string `json:"equipment"`
+	Experience int               `json:"experience"`
+	Ignored    []string          `json:"ignored"`
+	Muted      []uint8           `json:"muted_channels"`
+	Role       string            `json:"role"`
+}
+
+func accountPath(name string) string {
+	return path.Join(ServerInstance.dataPath, "players", strings.ToLower(name)+".json")
+}
+
+// loadAccount restores the saved state of the player, new players simply keep their defaults
+func (p *Player) loadAccount() error {
+	fData, err := ioutil.ReadFile(accountPath(p.name))
+	if os.IsNotExist(err) {
+		log.Debug().Str("player", p.name).Msg("No account found, creating a new one")
+		return nil
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("player", p.name).Msg("Failed to read account from disk")
+		return err
+	}
+
+	account := &playerAccount{}
+	if err := json.Unmarshal(fData, account); err != nil {
+		log.Warn().Err(err).Str("player", p.name).Msg("failed to unmarshal account data")
+		return err
+	}
+
+	if account.Inventory != nil {
+		p.inventory = account.Inventory
+	}
+	if account.Attributes != nil {
+		p.attributes = *account.Attributes
+	}
+	if account.Equipment != nil {
+		p.equipment = account.Equipment
+	}
+
+	if account.Ignored != nil {
+		p.ignored = account.Ignored
+	}
+	for _, channel := range account.Muted {
+		p.mutedChannels[channel] = true
+	}
+	for role, name := range roleNames {
+		if account.Role == name {
+			p.role = role
+		}
+	}
+
+	// The level is derived from the experience so that changes to the level curve apply to existing players
+	p.experience = account.Experience
+	p.level = ServerInstance.progression.levelFor(p.experience)
+
+	p.derived = calculateStats(p.attributes, p.level, p.equipment)
+	p.stats = p.derived.combat
+	p.maxhp = p.derived.maxHP
+	p.hp = p.maxhp
+	return nil
+}
+
+// saveAccount writes the player state to the data directory
+func (p *Player) saveAccount() {
+	muted := make([]uint8, 0, len(p.mutedChannels))
+	for channel := range p.mutedChannels {
+		muted = append(muted, channel)
+	}
+
+	account := playerAccount{
+		Name:       p.name,
+		Inventory:  p.inventory,
+		Attributes: &p.attributes,
+		Equipment:  p.equipment,
+		Experience: p.experience,
+		Ignored:    p.ignored,
+		Muted:      muted,
+		Role:       roleNames[p.role],
+	}
+
+	dir := path.Join(ServerInstance.dataPath, "players")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.Mkdir(dir, 0777); err != nil {
+			log.Warn().Err(err).Msg("Failed to create players path")
+			return
+		}
+	}
+
+	jData, _ := json.MarshalIndent(account, "", " ")
+	if err := ioutil.WriteFile(accountPath(p.name), jData, 0666); err != nil {
+		log.Warn().Err(err).Str("player", p.name).Msg("Failed to write account to disk")
+	}
+}
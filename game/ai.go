@@ -0,0 +1,180 @@
+package game
+
+import (
+	"math/rand"
+	"time"
+)
+
+type aiState uint8
+
+const (
+	AI_STATE_IDLE = aiState(iota)
+	AI_STATE_WANDER
+	AI_STATE_PATROL
+	AI_STATE_CHASE
+	AI_STATE_FLEE
+	AI_STATE_RETURN
+)
+
+// npcBehaviour configures the ai of every npc created from a template
+type npcBehaviour struct {
+	Wander         bool `json:"wander"`
+	WanderRadius   int  `json:"wander_radius"`
+	Patrol         bool `json:"patrol"`
+	Aggressive     bool `json:"aggressive"`
+	AggroRange     int  `json:"aggro_range"`
+	LeashRange     int  `json:"leash_range"`
+	FleePercent    int  `json:"flee_percent"`
+	MoveIntervalMs int  `json:"move_interval_ms"`
+}
+
+// npcBrain holds the ai state of a single npc
+type npcBrain struct {
+	state    aiState
+	target   *Player
+	home     Vector2
+	waypoint int
+	nextMove time.Time
+}
+
+var directions4 = []Vector2{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+// think evaluates the npc state machine, it is called on every game loop tick
+func (n *NPC) think(room *Room, now time.Time) {
+	b := n.template.Behaviour
+	n.brain.state = n.nextState(room)
+
+	if n.brain.state == AI_STATE_CHASE && n.readyToAttack(now) {
+		attackType := ATTACK_TYPE_MELEE
+		if n.template.Stats.AttackRange > meleeRange {
+			attackType = ATTACK_TYPE_RANGED
+		}
+		if validateAttack(room, n, n.brain.target, attackType, now) == ATTACK_ACCEPTED {
+			performAttack(room, n, n.brain.target, attackType, now)
+			return
+		}
+	}
+
+	if now.Before(n.brain.nextMove) {
+		return
+	}
+	n.brain.nextMove = now.Add(time.Duration(b.MoveIntervalMs) * time.Millisecond)
+
+	switch n.brain.state {
+	case AI_STATE_CHASE:
+		target := n.brain.target.position
+		reach := meleeRange
+		if n.template.Stats.AttackRange > reach {
+			reach = n.template.Stats.AttackRange
+		}
+		if distance(n.position, target) > reach || !room.hasLineOfSight(n.position, target) {
+			n.stepTowards(room, target)
+		}
+	case AI_STATE_FLEE:
+		n.stepAway(room, n.brain.target.position)
+	case AI_STATE_PATROL:
+		waypoints := n.spawner.Waypoints
+		if n.position == waypoints[n.brain.waypoint] {
+			n.brain.waypoint = (n.brain.waypoint + 1) % len(waypoints)
+		}
+		n.stepTowards(room, waypoints[n.brain.waypoint])
+	case AI_STATE_RETURN:
+		n.stepTowards(room, n.brain.home)
+	case AI_STATE_WANDER:
+		// Don't move on every opportunity, it looks more natural
+		if rand.Intn(3) != 0 {
+			return
+		}
+		dir := directions4[rand.Intn(len(directions4))]
+		pos := Vector2{n.position.X + dir.X, n.position.Y + dir.Y}
+		if distance(pos, n.brain.home) <= b.WanderRadius {
+			room.moveEntity(n, pos)
+		}
+	}
+}
+
+// nextState picks the state the npc should be in based on its surroundings
+func (n *NPC) nextState(room *Room) aiState {
+	b := n.template.Behaviour
+
+	if n.brain.target != nil {
+		t := n.brain.target
+		if t.currentRoom != room.ID || t.hp <= 0 || distance(n.position, t.position) > b.LeashRange {
+			n.brain.target = nil
+		}
+	}
+
+	if n.brain.target == nil && b.Aggressive {
+		n.brain.target = n.findTarget(room)
+	}
+
+	if n.brain.target != nil {
+		if b.FleePercent > 0 && n.hp*100 <= n.template.MaxHP*b.FleePercent {
+			return AI_STATE_FLEE
+		}
+		return AI_STATE_CHASE
+	}
+
+	if b.Patrol && n.spawner != nil && len(n.spawner.Waypoints) > 0 {
+		return AI_STATE_PATROL
+	}
+
+	if distance(n.position, n.brain.home) > b.WanderRadius {
+		return AI_STATE_RETURN
+	}
+
+	if b.Wander {
+		return AI_STATE_WANDER
+	}
+	return AI_STATE_IDLE
+}
+
+// findTarget returns the closest living player within aggro range that the npc can see
+func (n *NPC) findTarget(room *Room) *Player {
+	var closest *Player
+	for _, p := range room.players() {
+		d := distance(n.position, p.position)
+		if p.hp <= 0 || d > n.template.Behaviour.AggroRange || !room.hasLineOfSight(n.position, p.position) {
+			continue
+		}
+		if closest == nil || d < distance(n.position, closest.position) {
+			closest = p
+		}
+	}
+	return closest
+}
+
+func (n *NPC) stepTowards(room *Room, target Vector2) {
+	if step, ok := room.nextStepTowards(n.position, target); ok {
+		room.moveEntity(n, step)
+	}
+}
+
+// stepAway moves to the neighbouring tile that is the furthest from the threat
+func (n *NPC) stepAway(room *Room, threat Vector2) {
+	best := n.position
+	for _, dir := range directions4 {
+		pos := Vector2{n.position.X + dir.X, n.position.Y + dir.Y}
+		if room.isWalkable(pos) && distance(pos, threat) > distance(best, threat) {
+			best = pos
+		}
+	}
+	if best != n.position {
+		room.moveEntity(n, best)
+	}
+}
+
+// distance returns the chebyshev distance between two positions
+func distance(a, b Vector2) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
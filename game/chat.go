@@ -0,0 +1,218 @@
+package game
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	CHAT_CHANNEL_SAY = uint8(iota)
+	CHAT_CHANNEL_SHOUT
+	CHAT_CHANNEL_WHISPER
+	CHAT_CHANNEL_PARTY
+	chatChannelCount
+)
+
+const (
+	CHAT_OK = uint8(iota)
+	CHAT_ERROR_NOT_IN_WORLD
+	CHAT_ERROR_INVALID_CHANNEL
+	CHAT_ERROR_EMPTY
+	CHAT_ERROR_TOO_LONG
+	CHAT_ERROR_RATE_LIMITED
+	CHAT_ERROR_MUTED
+	CHAT_ERROR_NO_SUCH_PLAYER
+	CHAT_ERROR_NOT_IN_PARTY
+	CHAT_ERROR_REPEATED
+	CHAT_ERROR_PARTY_FULL
+)
+
+const (
+	defaultChatMaxLength       = 256
+	defaultChatRateMessages    = 5
+	defaultChatRateSeconds     = 5
+	defaultChatSpamMuteSeconds = 30
+)
+
+// chatState keeps track of a player's recent messages for spam protection
+type chatState struct {
+	sent        []time.Time
+	lastMessage string
+	mutedUntil  time.Time
+}
+
+func chatMaxLength() int {
+	if ServerInstance.config.Chat.MaxMessageLength > 0 {
+		return ServerInstance.config.Chat.MaxMessageLength
+	}
+	return defaultChatMaxLength
+}
+
+// checkRateLimit records the message and reports whether the player is allowed to send it.
+// Going over the limit mutes the player for a while.
+func (p *Player) checkRateLimit(message string, now time.Time) uint8 {
+	conf := ServerInstance.config.Chat
+	limit, window, muteFor := conf.RateLimitMessages, conf.RateLimitSeconds, conf.SpamMuteSeconds
+	if limit <= 0 {
+		limit = defaultChatRateMessages
+	}
+	if window <= 0 {
+		window = defaultChatRateSeconds
+	}
+	if muteFor <= 0 {
+		muteFor = defaultChatSpamMuteSeconds
+	}
+
+	if now.Before(p.chat.mutedUntil) {
+		return CHAT_ERROR_MUTED
+	}
+
+	since := now.Add(-time.Duration(window) * time.Second)
+	recent := p.chat.sent[:0]
+	for _, t := range p.chat.sent {
+		if t.After(since) {
+			recent = append(recent, t)
+		}
+	}
+	p.chat.sent = recent
+
+	if len(recent) > 0 && strings.EqualFold(message, p.chat.lastMessage) {
+		return CHAT_ERROR_REPEATED
+	}
+	if len(recent) >= limit {
+		p.chat.mutedUntil = now.Add(time.Duration(muteFor) * time.Second)
+		return CHAT_ERROR_RATE_LIMITED
+	}
+
+	p.chat.sent = append(p.chat.sent, now)
+	p.chat.lastMessage = message
+	return CHAT_OK
+}
+
+// filterProfanity masks every configured word in the message
+func filterProfanity(message string) string {
+	lower := strings.ToLower(message)
+	if len(lower) != len(message) {
+		// Some characters change their length when lowered, only match exact case then
+		lower = message
+	}
+	for _, word := range ServerInstance.config.Chat.Profanity {
+		word = strings.ToLower(word)
+		if word == "" {
+			continue
+		}
+		// Carry on after every match, a word made of stars would otherwise match its own mask forever
+		mask := strings.Repeat("*", len(word))
+		for start := 0; ; {
+			idx := strings.Index(lower[start:], word)
+			if idx < 0 {
+				break
+			}
+			idx += start
+			message = message[:idx] + mask + message[idx+len(word):]
+			lower = lower[:idx] + mask + lower[idx+len(word):]
+			start = idx + len(word)
+		}
+	}
+	return message
+}
+
+// isIgnoring reports whether the player has put the other player on their ignore list
+func (p *Player) isIgnoring(other *Player) bool {
+	for _, name := range p.ignored {
+		if strings.EqualFold(name, other.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// receivesChat checks the ignore list and muted channels of the recipient
+func (p *Player) receivesChat(from *Player, channel uint8) bool {
+	if p.connection == nil || p.isIgnoring(from) {
+		return false
+	}
+	return channel == CHAT_CHANNEL_WHISPER || !p.mutedChannels[channel]
+}
+
+// sendChat validates the message and delivers it to everyone on the channel
+func sendChat(from *Player, channel uint8, target, message string) uint8 {
+	if from == nil {
+		return CHAT_ERROR_NOT_IN_WORLD
+	}
+	if channel >= chatChannelCount {
+		return CHAT_ERROR_INVALID_CHANNEL
+	}
+
+	message = strings.TrimSpace(message)
+	if message == "" || !utf8.ValidString(message) {
+		return CHAT_ERROR_EMPTY
+	}
+	if utf8.RuneCountInString(message) > chatMaxLength() {
+		return CHAT_ERROR_TOO_LONG
+	}
+
+	recipients := make([]*Player, 0)
+	switch channel {
+	case CHAT_CHANNEL_SAY:
+		room := ServerInstance.FindRoom(from.currentRoom.String())
+		if room == nil {
+			return CHAT_ERROR_NOT_IN_WORLD
+		}
+		recipients = room.players()
+	case CHAT_CHANNEL_SHOUT:
+		recipients = ServerInstance.players()
+	case CHAT_CHANNEL_WHISPER:
+		to := ServerInstance.findPlayerByName(target)
+		if to == nil {
+			return CHAT_ERROR_NO_SUCH_PLAYER
+		}
+		recipients = append(recipients, to)
+		if to != from {
+			recipients = append(recipients, from)
+		}
+	case CHAT_CHANNEL_PARTY:
+		if from.party == nil {
+			return CHAT_ERROR_NOT_IN_PARTY
+		}
+		recipients = from.party.members
+	}
+
+	if reason := from.checkRateLimit(message, time.Now()); reason != CHAT_OK {
+		return reason
+	}
+
+	pkt := NewPacket(MsgChatMessage)
+	pkt.WriteUint8(channel)
+	pkt.WriteUint64(uint64(from.id))
+	pkt.WriteString(from.name)
+	pkt.WriteString(filterProfanity(message))
+	for _, p := range recipients {
+		if p == from || p.receivesChat(from, channel) {
+			sendMessageToConnection(p.connection, *pkt)
+		}
+	}
+	return CHAT_OK
+}
+
+// players returns every player that is currently in the world
+func (server *Server) players() []*Player {
+	list := make([]*Player, 0)
+	for _, c := range server.connectionsList {
+		if c.player != nil {
+			list = append(list, c.player)
+		}
+	}
+	return list
+}
+
+// findPlayerByName looks up an online player, names are not case sensitive
+func (server *Server) findPlayerByName(name string) *Player {
+	for _, p := range server.players() {
+		if strings.EqualFold(p.name, name) {
+			return p
+		}
+	}
+	return nil
+}
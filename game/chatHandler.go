@@ -0,0 +1,105 @@
+package game
+
+import "strings"
+
+type ChatHandler struct{}
+
+/*
+*****************************
+CHAT REQUEST STRUCTURE
+*****************************
+1 byte - channel (0 - say, 1 - shout, 2 - whisper, 3 - party)
+2 bytes + <n> bytes - whisper target name, empty for every other channel
+2 bytes + <n> bytes - message
+
+Delivered messages:
+1 byte - channel
+8 bytes - uint64 sender entity id
+2 bytes + <n> bytes - sender name
+2 bytes + <n> bytes - message
+
+Errors:
+1 byte - reason
+*/
+func (h ChatHandler) handle(packet *Packet) {
+	channel := packet.ReadUint8()
+	target := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	message := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+
+	if reason := sendChat(packet.Connection.player, channel, target, message); reason != CHAT_OK {
+		sendChatError(packet.Connection, reason)
+	}
+}
+
+type ChatIgnoreHandler struct{}
+
+/*
+*****************************
+CHAT IGNORE REQUEST STRUCTURE
+*****************************
+1 byte - bool ignore (false removes the player from the list)
+2 bytes + <n> bytes - player name
+*/
+func (h ChatIgnoreHandler) handle(packet *Packet) {
+	ignore := packet.ReadBoolean()
+	name := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+
+	player := packet.Connection.player
+	if player == nil {
+		sendChatError(packet.Connection, CHAT_ERROR_NOT_IN_WORLD)
+		return
+	}
+	if !validPlayerName.MatchString(name) {
+		sendChatError(packet.Connection, CHAT_ERROR_NO_SUCH_PLAYER)
+		return
+	}
+
+	list := make([]string, 0, len(player.ignored)+1)
+	for _, n := range player.ignored {
+		if !strings.EqualFold(n, name) {
+			list = append(list, n)
+		}
+	}
+	if ignore {
+		list = append(list, name)
+	}
+	player.ignored = list
+	player.saveAccount()
+}
+
+type ChatChannelMuteHandler struct{}
+
+/*
+**********************************
+CHAT CHANNEL MUTE REQUEST STRUCTURE
+**********************************
+1 byte - channel, whispers can not be muted, use the ignore list instead
+1 byte - bool muted
+*/
+func (h ChatChannelMuteHandler) handle(packet *Packet) {
+	channel := packet.ReadUint8()
+	muted := packet.ReadBoolean()
+
+	player := packet.Connection.player
+	if player == nil {
+		sendChatError(packet.Connection, CHAT_ERROR_NOT_IN_WORLD)
+		return
+	}
+	if channel >= chatChannelCount || channel == CHAT_CHANNEL_WHISPER {
+		sendChatError(packet.Connection, CHAT_ERROR_INVALID_CHANNEL)
+		return
+	}
+
+	if muted {
+		player.mutedChannels[channel] = true
+	} else {
+		delete(player.mutedChannels, channel)
+	}
+	player.saveAccount()
+}
+
+func sendChatError(connection *Connection, reason uint8) {
+	pkt := NewPacket(MsgChatError)
+	pkt.WriteUint8(reason)
+	sendMessageToConnection(connection, *pkt)
+}
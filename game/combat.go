@@ -0,0 +1,246 @@
+package game
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ATTACK_TYPE_MELEE = uint8(iota)
+	ATTACK_TYPE_RANGED
+)
+
+const (
+	ATTACK_RESULT_MISS = uint8(iota)
+	ATTACK_RESULT_HIT
+	ATTACK_RESULT_CRIT
+)
+
+const (
+	meleeRange             = 1
+	rangedRange            = 6
+	defaultAttackInterval  = 1000
+	baseHitChance          = 75
+	critDamageMultiplier   = 2
+	minHitChance           = 5
+	maxHitChance           = 95
+	rangedDamagePercentage = 80
+)
+
+// combatStats are the numbers that drive the combat formulas
+type combatStats struct {
+	Attack           int `json:"attack"`
+	Defence          int `json:"defence"`
+	Accuracy         int `json:"accuracy"`
+	Evasion          int `json:"evasion"`
+	CritChance       int `json:"crit_chance"`
+	AttackRange      int `json:"attack_range"`
+	AttackIntervalMs int `json:"attack_interval_ms"`
+}
+
+// combatant is an entity that can attack and be attacked
+type combatant interface {
+	entity
+	getCombatStats() combatStats
+	takeDamage(amount int)
+	readyToAttack(now time.Time) bool
+	setNextAttack(next time.Time)
+}
+
+// attackResult is the outcome of a single attack
+type attackResult struct {
+	result uint8
+	damage int
+	died   bool
+}
+
+// rollAttack decides whether the attack hits and how much damage it deals
+func rollAttack(attacker, target combatStats, attackType uint8) attackResult {
+	rng := ServerInstance.rng
+
+	hitChance := baseHitChance + attacker.Accuracy - target.Evasion
+	if hitChance < minHitChance {
+		hitChance = minHitChance
+	} else if hitChance > maxHitChance {
+		hitChance = maxHitChance
+	}
+	if rng.Intn(100) >= hitChance {
+		return attackResult{result: ATTACK_RESULT_MISS}
+	}
+
+	damage := attacker.Attack
+	if spread := attacker.Attack / 4; spread > 0 {
+		damage += rng.Intn(spread + 1)
+	}
+	if attackType == ATTACK_TYPE_RANGED {
+		damage = damage * rangedDamagePercentage / 100
+	}
+	damage -= target.Defence / 2
+
+	result := ATTACK_RESULT_HIT
+	if rng.Intn(100) < attacker.CritChance {
+		result = ATTACK_RESULT_CRIT
+		damage *= critDamageMultiplier
+	}
+	if damage < 1 {
+		damage = 1
+	}
+	return attackResult{result: result, damage: damage}
+}
+
+/*
+performAttack resolves an attack that has already been validated and broadcasts it to the room
+
+*****************************
+COMBAT EVENT STRUCTURE
+*****************************
+8 bytes - uint64 attacker id
+8 bytes - uint64 target id
+1 byte - attack type (0 - melee, 1 - ranged)
+1 byte - result (0 - miss, 1 - hit, 2 - critical hit)
+2 bytes - uint16 damage
+2 bytes - uint16 target hp left
+1 byte - bool target died
+*/
+func performAttack(room *Room, attacker, target combatant, attackType uint8, now time.Time) attackResult {
+	stats := attacker.getCombatStats()
+	interval := stats.AttackIntervalMs
+	if interval <= 0 {
+		interval = defaultAttackInterval
+	}
+	attacker.setNextAttack(now.Add(time.Duration(interval) * time.Millisecond))
+
+	outcome := rollAttack(stats, target.getCombatStats(), attackType)
+	if outcome.damage > 0 {
+		target.takeDamage(outcome.damage)
+	}
+	outcome.died = target.getCurrentHP() <= 0
+
+	pkt := NewPacket(MsgCombatEvent)
+	pkt.WriteUint64(uint64(attacker.getID()))
+	pkt.WriteUint64(uint64(target.getID()))
+	pkt.WriteUint8(attackType)
+	pkt.WriteUint8(outcome.result)
+	pkt.WriteUint16(uint16(outcome.damage))
+	pkt.WriteUint16(uint16(target.getCurrentHP()))
+	pkt.WriteBool(outcome.died)
+	room.broadcast(pkt)
+
+	// Monsters fight back against whoever hit them
+	if npc, ok := target.(*NPC); ok && npc.template.Kind == NPC_KIND_MONSTER {
+		if p, ok := attacker.(*Player); ok && npc.brain.target == nil {
+			npc.brain.target = p
+		}
+	}
+
+	if outcome.died {
+		onDeath(room, target, attacker)
+	}
+	return outcome
+}
+
+// onDeath removes dead npcs from the world leaving their loot behind and sends dead players back to the starting room
+func onDeath(room *Room, victim, killer combatant) {
+	log.Debug().Str("victim", victim.getName()).Str("killer", killer.getName()).Str("room", room.ID.String()).Msg("Entity died")
+
+	switch v := victim.(type) {
+	case *NPC:
+		room.removeEntity(v, DESPAWN_REASON_DIED)
+		if p, ok := killer.(*Player); ok {
+			p.awardExperience(v.template.Experience, XP_SOURCE_KILL)
+		}
+		for _, loot := range v.template.Loot {
+			if ServerInstance.rng.Intn(100) < loot.Chance {
+				dropItem(room, itemStack{Template: loot.Item, Quantity: loot.Quantity}, v.position)
+			}
+		}
+	case *Player:
+		respawnPlayer(v, room)
+	}
+}
+
+// respawnPlayer restores the player and places them at the entry of the starting room
+func respawnPlayer(p *Player, room *Room) {
+	room.removeEntity(p, DESPAWN_REASON_DIED)
+	p.clearPath()
+	p.hp = p.maxhp
+	p.sendCharacterSheet()
+
+	start := ServerInstance.findStartingRoom()
+	if start == nil {
+		start = room
+	}
+	placePlayerInRoom(p, start, start.Entry.LocationInRoom)
+}
+
+// validateAttack checks the range and line of sight between attacker and target
+func validateAttack(room *Room, attacker, target combatant, attackType uint8, now time.Time) uint8 {
+	if attacker.getCurrentHP() <= 0 {
+		return ATTACK_REJECTED_DEAD
+	}
+	if target.getRoomID() != room.ID {
+		return ATTACK_REJECTED_INVALID_TARGET
+	}
+	if target.getCurrentHP() <= 0 {
+		return ATTACK_REJECTED_TARGET_DEAD
+	}
+	if !attacker.readyToAttack(now) {
+		return ATTACK_REJECTED_COOLDOWN
+	}
+
+	// Only ranged attacks reach as far as the weapon does, hitting someone with a bow is still melee
+	maxRange := meleeRange
+	if attackType == ATTACK_TYPE_RANGED {
+		maxRange = rangedRange
+		if r := attacker.getCombatStats().AttackRange; r > maxRange {
+			maxRange = r
+		}
+	}
+	if distance(attacker.getPosition(), target.getPosition()) > maxRange {
+		return ATTACK_REJECTED_OUT_OF_RANGE
+	}
+	if !room.hasLineOfSight(attacker.getPosition(), target.getPosition()) {
+		return ATTACK_REJECTED_NO_LINE_OF_SIGHT
+	}
+	return ATTACK_ACCEPTED
+}
+
+func (p *Player) getCombatStats() combatStats {
+	return p.stats
+}
+
+func (p *Player) takeDamage(amount int) {
+	p.hp -= amount
+	if p.hp < 0 {
+		p.hp = 0
+	}
+	p.sendCharacterSheet()
+}
+
+func (p *Player) readyToAttack(now time.Time) bool {
+	return !now.Before(p.nextAttack)
+}
+
+func (p *Player) setNextAttack(next time.Time) {
+	p.nextAttack = next
+}
+
+func (n *NPC) getCombatStats() combatStats {
+	return n.template.Stats
+}
+
+func (n *NPC) takeDamage(amount int) {
+	n.hp -= amount
+	if n.hp < 0 {
+		n.hp = 0
+	}
+}
+
+func (n *NPC) readyToAttack(now time.Time) bool {
+	return !now.Before(n.nextAttack)
+}
+
+func (n *NPC) setNextAttack(next time.Time) {
+	n.nextAttack = next
+}
@@ -0,0 +1,69 @@
+package game
+
+import "time"
+
+const (
+	ATTACK_ACCEPTED = uint8(iota)
+	ATTACK_REJECTED_NOT_IN_WORLD
+	ATTACK_REJECTED_INVALID_TARGET
+	ATTACK_REJECTED_OUT_OF_RANGE
+	ATTACK_REJECTED_NO_LINE_OF_SIGHT
+	ATTACK_REJECTED_COOLDOWN
+	ATTACK_REJECTED_DEAD
+	ATTACK_REJECTED_INVALID_TYPE
+	ATTACK_REJECTED_TARGET_DEAD
+)
+
+type AttackHandler struct{}
+
+/*
+*****************************
+ATTACK REQUEST STRUCTURE
+*****************************
+8 bytes - uint64 target entity id
+1 byte - attack type (0 - melee, 1 - ranged)
+
+Rejections are answered with:
+8 bytes - uint64 target entity id
+1 byte - reason
+*/
+func (h AttackHandler) handle(packet *Packet) {
+	targetID := int64(packet.ReadUint64())
+	attackType := packet.ReadUint8()
+
+	reason := attack(packet.Connection.player, targetID, attackType)
+	if reason != ATTACK_ACCEPTED {
+		response := NewPacket(MsgAttackRejected)
+		response.WriteUint64(uint64(targetID))
+		response.WriteUint8(reason)
+		sendMessageToConnection(packet.Connection, *response)
+	}
+}
+
+// attack validates and performs an attack of a player against another entity
+func attack(player *Player, targetID int64, attackType uint8) uint8 {
+	if player == nil {
+		return ATTACK_REJECTED_NOT_IN_WORLD
+	}
+	if attackType > ATTACK_TYPE_RANGED {
+		return ATTACK_REJECTED_INVALID_TYPE
+	}
+
+	room := ServerInstance.FindRoom(player.currentRoom.String())
+	if room == nil {
+		return ATTACK_REJECTED_NOT_IN_WORLD
+	}
+
+	// Only monsters can be attacked, there is no player versus player combat
+	npc, ok := room.entities[targetID].(*NPC)
+	if !ok || npc.template.Kind != NPC_KIND_MONSTER {
+		return ATTACK_REJECTED_INVALID_TARGET
+	}
+
+	now := time.Now()
+	if reason := validateAttack(room, player, npc, attackType, now); reason != ATTACK_ACCEPTED {
+		return reason
+	}
+	performAttack(room, player, npc, attackType, now)
+	return ATTACK_ACCEPTED
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func seedCombat(seed int64) {
+	ServerInstance = &Server{rng: rand.New(rand.NewSource(seed)), config: &serverConfig{}, roomList: map[string]*Room{}}
+}
+
+func TestRollAttackIsRepeatableWithASeed(t *testing.T) {
+	attacker := combatStats{Attack: 20, Accuracy: 10, CritChance: 10}
+	target := combatStats{Defence: 4, Evasion: 5}
+
+	seedCombat(42)
+	first := make([]attackResult, 50)
+	for i := range first {
+		first[i] = rollAttack(attacker, target, ATTACK_TYPE_MELEE)
+	}
+	seedCombat(42)
+	for i := range first {
+		if again := rollAttack(attacker, target, ATTACK_TYPE_MELEE); again != first[i] {
+			t.Fatalf("Expected roll %d to be %+v with the same seed but was %+v", i, first[i], again)
+		}
+	}
+}
+
+func TestRollAttackHitsAndMisses(t *testing.T) {
+	seedCombat(7)
+	accurate := combatStats{Attack: 20, Accuracy: 100}
+	clumsy := combatStats{Attack: 20, Accuracy: -100}
+
+	hits, misses := 0, 0
+	for i := 0; i < 1000; i++ {
+		if rollAttack(accurate, combatStats{}, ATTACK_TYPE_MELEE).result != ATTACK_RESULT_MISS {
+			hits++
+		}
+		if r := rollAttack(clumsy, combatStats{}, ATTACK_TYPE_MELEE); r.result == ATTACK_RESULT_MISS {
+			misses++
+			if r.damage != 0 {
+				t.Fatalf("Expected a miss to deal no damage but it dealt %d", r.damage)
+			}
+		}
+	}
+	// The hit chance is clamped between 5 and 95 percent
+	if hits < 900 || hits == 1000 {
+		t.Fatalf("Expected about 95%% of accurate attacks to hit but %d of 1000 did", hits)
+	}
+	if misses < 900 || misses == 1000 {
+		t.Fatalf("Expected about 95%% of clumsy attacks to miss but %d of 1000 did", misses)
+	}
+}
+
+func TestRollAttackDamage(t *testing.T) {
+	seedCombat(3)
+	attacker := combatStats{Attack: 20, Accuracy: 100}
+	target := combatStats{Defence: 4}
+
+	for i := 0; i < 500; i++ {
+		r := rollAttack(attacker, target, ATTACK_TYPE_MELEE)
+		// 20 attack plus up to a quarter of it, minus half the defence
+		if r.result == ATTACK_RESULT_HIT && (r.damage < 18 || r.damage > 23) {
+			t.Fatalf("Expected melee damage between 18 and 23 but it was %d", r.damage)
+		}
+		r = rollAttack(attacker, target, ATTACK_TYPE_RANGED)
+		if r.result == ATTACK_RESULT_HIT && (r.damage < 14 || r.damage > 18) {
+			t.Fatalf("Expected ranged damage between 14 and 18 but it was %d", r.damage)
+		}
+	}
+
+	crit := rollAttack(combatStats{Attack: 20, Accuracy: 100, CritChance: 100}, target, ATTACK_TYPE_MELEE)
+	for crit.result == ATTACK_RESULT_MISS {
+		crit = rollAttack(combatStats{Attack: 20, Accuracy: 100, CritChance: 100}, target, ATTACK_TYPE_MELEE)
+	}
+	if crit.result != ATTACK_RESULT_CRIT || crit.damage < 36 || crit.damage > 46 {
+		t.Fatalf("Expected a critical hit dealing double damage but got %+v", crit)
+	}
+}
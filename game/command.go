@@ -0,0 +1,212 @@
+package game
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	ROLE_PLAYER = uint8(iota)
+	ROLE_BUILDER
+	ROLE_ADMIN
+)
+
+var roleNames = map[uint8]string{
+	ROLE_PLAYER:  "player",
+	ROLE_BUILDER: "builder",
+	ROLE_ADMIN:   "admin",
+}
+
+// validRoleToken reports whether the token lets a player join with the role. Builders join with the builder token
+// or the admin token, admins only with the admin token. There is no valid token for a role whose token isn't set.
+func (server *Server) validRoleToken(role uint8, token string) bool {
+	if role == ROLE_BUILDER && sameToken(token, server.config.BuilderToken) {
+		return true
+	}
+	return sameToken(token, server.config.AdminToken)
+}
+
+func sameToken(token, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// isAdmin reports whether the config names the player as an admin
+func (server *Server) isAdmin(name string) bool {
+	for _, admin := range server.config.Admins {
+		if strings.EqualFold(admin, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// command is a single verb of the text command language
+type command struct {
+	name        string
+	aliases     []string
+	usage       string
+	description string
+	minArgs     int
+	role        uint8
+	run         func(ctx *commandContext, args []string)
+}
+
+// commandContext is passed to every command and collects its output
+type commandContext struct {
+	player *Player
+	output []string
+}
+
+func (ctx *commandContext) reply(format string, a ...interface{}) {
+	ctx.output = append(ctx.output, fmt.Sprintf(format, a...))
+}
+
+var (
+	commands        = map[string]*command{}
+	commandAliases  = map[string]string{}
+	commandShortcut = map[string]string{
+		"n":  "go north",
+		"e":  "go east",
+		"s":  "go south",
+		"w":  "go west",
+		"ne": "go northeast",
+		"se": "go southeast",
+		"sw": "go southwest",
+		"nw": "go northwest",
+		"'":  "say",
+		"l":  "look",
+		"i":  "inventory",
+	}
+)
+
+// registerCommand adds the command to the registry, panics on duplicate names as that is a programming error
+func registerCommand(c *command) {
+	for _, name := range append([]string{c.name}, c.aliases...) {
+		if _, found := commands[name]; found {
+			panic(fmt.Sprintf("command %s registered twice", name))
+		}
+		if _, found := commandAliases[name]; found {
+			panic(fmt.Sprintf("command %s registered twice", name))
+		}
+	}
+	commands[c.name] = c
+	for _, alias := range c.aliases {
+		commandAliases[alias] = c.name
+	}
+}
+
+// findCommand looks up a command by name or alias
+func findCommand(name string) *command {
+	name = strings.ToLower(name)
+	if c, found := commands[name]; found {
+		return c
+	}
+	if real, found := commandAliases[name]; found {
+		return commands[real]
+	}
+	return nil
+}
+
+// tokenize splits a command line on whitespace, double quotes group words together
+func tokenize(line string) []string {
+	tokens := make([]string, 0)
+	var current strings.Builder
+	quoted, started := false, false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if started {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// executeCommand parses and runs a raw command line for the player and returns the output lines
+func executeCommand(player *Player, line string) []string {
+	ctx := &commandContext{player: player}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return ctx.output
+	}
+
+	// Shortcuts such as ' for say don't need a space after them
+	if strings.HasPrefix(line, "'") {
+		line = "say " + line[1:]
+	}
+
+	tokens := tokenize(line)
+	if len(tokens) == 0 || tokens[0] == "" {
+		return ctx.output
+	}
+	if expanded, found := commandShortcut[strings.ToLower(tokens[0])]; found {
+		tokens = append(tokenize(expanded), tokens[1:]...)
+	}
+
+	c := findCommand(tokens[0])
+	if c == nil || player.role < c.role {
+		ctx.reply("Unknown command '%s', type 'help' for a list of commands.", tokens[0])
+		return ctx.output
+	}
+
+	args := tokens[1:]
+	if len(args) < c.minArgs {
+		ctx.reply("Usage: %s", c.usage)
+		return ctx.output
+	}
+
+	c.run(ctx, args)
+	return ctx.output
+}
+
+// helpText generates the help for every command available to the role, or for a single command
+func helpText(role uint8, topic string) []string {
+	if topic != "" {
+		c := findCommand(topic)
+		if c == nil || role < c.role {
+			return []string{fmt.Sprintf("There is no help for '%s'.", topic)}
+		}
+		lines := []string{fmt.Sprintf("%s - %s", c.usage, c.description)}
+		if len(c.aliases) > 0 {
+			lines = append(lines, fmt.Sprintf("Aliases: %s", strings.Join(c.aliases, ", ")))
+		}
+		return lines
+	}
+
+	names := make([]string, 0, len(commands))
+	for name, c := range commands {
+		if role >= c.role {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	lines := []string{"Available commands:"}
+	for _, name := range names {
+		c := commands[name]
+		lines = append(lines, fmt.Sprintf("  %-28s %s", c.usage, c.description))
+	}
+	lines = append(lines, "Type 'help <command>' for more information.")
+	return lines
+}
+
+// joinArgs glues the arguments back together, used by commands taking free text
+func joinArgs(args []string) string {
+	return strings.Join(args, " ")
+}
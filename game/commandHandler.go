@@ -0,0 +1,34 @@
+package game
+
+import "strings"
+
+type CommandHandler struct{}
+
+/*
+*****************************
+COMMAND REQUEST STRUCTURE
+*****************************
+2 bytes + <n> bytes - raw command line, for example "get sword" or "go north"
+
+Response:
+2 bytes + <n> bytes - command output, lines are separated by a new line character
+*/
+func (h CommandHandler) handle(packet *Packet) {
+	line := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+
+	player := packet.Connection.player
+	if player == nil {
+		sendCommandOutput(packet.Connection, []string{"You have to join the world first."})
+		return
+	}
+
+	if output := executeCommand(player, line); len(output) > 0 {
+		sendCommandOutput(packet.Connection, output)
+	}
+}
+
+func sendCommandOutput(connection *Connection, lines []string) {
+	pkt := NewPacket(MsgCommandOutput)
+	pkt.WriteString(strings.Join(lines, "\n"))
+	sendMessageToConnection(connection, *pkt)
+}
@@ -0,0 +1,831 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// directionNames maps the names players type onto moveDirections
+var directionNames = map[string]int{
+	"north":     0,
+	"east":      1,
+	"south":     2,
+	"west":      3,
+	"northeast": 4,
+	"southeast": 5,
+	"southwest": 6,
+	"northwest": 7,
+}
+
+var inventoryResultText = map[uint8]string{
+	INVENTORY_ERROR_NOT_IN_WORLD:   "You are not in the world.",
+	INVENTORY_ERROR_NOT_FOUND:      "You don't see that here.",
+	INVENTORY_ERROR_TOO_FAR:        "That is too far away.",
+	INVENTORY_ERROR_FULL:           "Your inventory is full.",
+	INVENTORY_ERROR_TOO_HEAVY:      "That is too heavy to carry.",
+	INVENTORY_ERROR_NOT_USABLE:     "You can't use that.",
+	INVENTORY_ERROR_NOT_EQUIPPABLE: "You can't equip that.",
+}
+
+var attackResultText = map[uint8]string{
+	ATTACK_REJECTED_NOT_IN_WORLD:     "You are not in the world.",
+	ATTACK_REJECTED_INVALID_TARGET:   "You can't attack that.",
+	ATTACK_REJECTED_OUT_OF_RANGE:     "Your target is out of range.",
+	ATTACK_REJECTED_NO_LINE_OF_SIGHT: "You can't see your target.",
+	ATTACK_REJECTED_COOLDOWN:         "You are not ready to attack again.",
+	ATTACK_REJECTED_DEAD:             "You can't attack while you are dead.",
+	ATTACK_REJECTED_INVALID_TYPE:     "You don't know how to attack like that.",
+	ATTACK_REJECTED_TARGET_DEAD:      "Your target is already dead.",
+}
+
+var chatResultText = map[uint8]string{
+	CHAT_ERROR_NOT_IN_WORLD:    "You are not in the world.",
+	CHAT_ERROR_INVALID_CHANNEL: "You can't do that.",
+	CHAT_ERROR_EMPTY:           "What do you want to say?",
+	CHAT_ERROR_TOO_LONG:        "That message is too long.",
+	CHAT_ERROR_RATE_LIMITED:    "You are talking too fast.",
+	CHAT_ERROR_MUTED:           "You are muted.",
+	CHAT_ERROR_NO_SUCH_PLAYER:  "There is nobody by that name.",
+	CHAT_ERROR_NOT_IN_PARTY:    "You are not in a party.",
+	CHAT_ERROR_REPEATED:        "You just said that.",
+	CHAT_ERROR_PARTY_FULL:      "That party is full.",
+}
+
+func init() {
+	registerCommand(&command{
+		name: "help", aliases: []string{"?", "commands"}, usage: "help [command]",
+		description: "Lists the commands or explains one of them",
+		run: func(ctx *commandContext, args []string) {
+			ctx.output = append(ctx.output, helpText(ctx.player.role, joinArgs(args))...)
+		},
+	})
+	registerCommand(&command{
+		name: "look", usage: "look [target]",
+		description: "Describes the room or something in it",
+		run:         cmdLook,
+	})
+	registerCommand(&command{
+		name: "map", usage: "map",
+		description: "Draws the room around you",
+		run:         cmdMap,
+	})
+	registerCommand(&command{
+		name: "go", aliases: []string{"move", "walk"}, usage: "go <direction>",
+		description: "Moves a single step, n e s w and diagonals work as shortcuts",
+		minArgs:     1,
+		run:         cmdGo,
+	})
+	registerCommand(&command{
+		name: "get", aliases: []string{"take", "pickup"}, usage: "get <item>",
+		description: "Picks up an item lying next to you",
+		minArgs:     1,
+		run:         cmdGet,
+	})
+	registerCommand(&command{
+		name: "drop", usage: "drop <item> [quantity]",
+		description: "Drops an item from your inventory",
+		minArgs:     1,
+		run:         cmdDrop,
+	})
+	registerCommand(&command{
+		name: "use", aliases: []string{"drink", "eat"}, usage: "use <item>",
+		description: "Uses an item from your inventory",
+		minArgs:     1,
+		run:         cmdUse,
+	})
+	registerCommand(&command{
+		name: "equip", aliases: []string{"wear", "wield"}, usage: "equip <item>",
+		description: "Equips an item from your inventory",
+		minArgs:     1,
+		run:         cmdEquip,
+	})
+	registerCommand(&command{
+		name: "unequip", aliases: []string{"remove"}, usage: "unequip <slot>",
+		description: fmt.Sprintf("Takes off whatever is in the slot (%s)", strings.Join(equipmentSlots, ", ")),
+		minArgs:     1,
+		run:         cmdUnequip,
+	})
+	registerCommand(&command{
+		name: "inventory", aliases: []string{"inv"}, usage: "inventory",
+		description: "Lists what you are carrying",
+		run:         cmdInventory,
+	})
+	registerCommand(&command{
+		name: "score", aliases: []string{"stats", "sheet"}, usage: "score",
+		description: "Shows your character sheet",
+		run:         cmdScore,
+	})
+	registerCommand(&command{
+		name: "kill", aliases: []string{"attack", "k"}, usage: "kill <target>",
+		description: "Attacks a monster in melee",
+		minArgs:     1,
+		run: func(ctx *commandContext, args []string) {
+			cmdAttack(ctx, args, ATTACK_TYPE_MELEE)
+		},
+	})
+	registerCommand(&command{
+		name: "shoot", aliases: []string{"fire"}, usage: "shoot <target>",
+		description: "Attacks a monster from range",
+		minArgs:     1,
+		run: func(ctx *commandContext, args []string) {
+			cmdAttack(ctx, args, ATTACK_TYPE_RANGED)
+		},
+	})
+	registerCommand(&command{
+		name: "say", usage: "say <message>",
+		description: "Talks to everyone in the room",
+		minArgs:     1,
+		run: func(ctx *commandContext, args []string) {
+			cmdChat(ctx, CHAT_CHANNEL_SAY, "", joinArgs(args))
+		},
+	})
+	registerCommand(&command{
+		name: "shout", aliases: []string{"yell"}, usage: "shout <message>",
+		description: "Talks to everyone in the world",
+		minArgs:     1,
+		run: func(ctx *commandContext, args []string) {
+			cmdChat(ctx, CHAT_CHANNEL_SHOUT, "", joinArgs(args))
+		},
+	})
+	registerCommand(&command{
+		name: "whisper", aliases: []string{"tell", "msg"}, usage: "whisper <player> <message>",
+		description: "Talks to a single player",
+		minArgs:     2,
+		run: func(ctx *commandContext, args []string) {
+			cmdChat(ctx, CHAT_CHANNEL_WHISPER, args[0], joinArgs(args[1:]))
+		},
+	})
+	registerCommand(&command{
+		name: "party", aliases: []string{"p"}, usage: "party <message>",
+		description: "Talks to your party",
+		minArgs:     1,
+		run: func(ctx *commandContext, args []string) {
+			cmdChat(ctx, CHAT_CHANNEL_PARTY, "", joinArgs(args))
+		},
+	})
+	registerCommand(&command{
+		name: "invite", usage: "invite <player>",
+		description: "Invites a player to your party",
+		minArgs:     1,
+		run:         cmdInvite,
+	})
+	registerCommand(&command{
+		name: "accept", aliases: []string{"join"}, usage: "accept <player>",
+		description: "Accepts a party invite",
+		minArgs:     1,
+		run:         cmdAccept,
+	})
+	registerCommand(&command{
+		name: "leave", usage: "leave",
+		description: "Leaves your party",
+		run: func(ctx *commandContext, args []string) {
+			if ctx.player.party == nil {
+				ctx.reply(chatResultText[CHAT_ERROR_NOT_IN_PARTY])
+				return
+			}
+			ctx.player.leaveParty()
+			ctx.reply("You leave the party.")
+		},
+	})
+	registerCommand(&command{
+		name: "who", usage: "who",
+		description: "Lists everyone who is online",
+		run:         cmdWho,
+	})
+	registerCommand(&command{
+		name: "colour", aliases: []string{"color"}, usage: "colour <on|off>",
+		description: "Turns ANSI colours on or off for text clients",
+		minArgs:     1,
+		run:         cmdColour,
+	})
+	registerCommand(&command{
+		name: "quit", usage: "quit",
+		description: "Leaves the game",
+		run: func(ctx *commandContext, args []string) {
+			ctx.player.connection.transport.Close()
+		},
+	})
+	registerCommand(&command{
+		name: "goto", usage: "goto <room id>",
+		description: "Teleports you to the entry of a room",
+		minArgs:     1,
+		role:        ROLE_BUILDER,
+		run:         cmdGoto,
+	})
+	registerCommand(&command{
+		name: "undo", usage: "undo",
+		description: "Takes back the last edit made in the room you are in",
+		role:        ROLE_BUILDER,
+		run: func(ctx *commandContext, args []string) {
+			cmdRoomHistory(ctx, (*Room).undoEdit)
+		},
+	})
+	registerCommand(&command{
+		name: "redo", usage: "redo",
+		description: "Applies the last undone edit in the room you are in again",
+		role:        ROLE_BUILDER,
+		run: func(ctx *commandContext, args []string) {
+			cmdRoomHistory(ctx, (*Room).redoEdit)
+		},
+	})
+	registerCommand(&command{
+		name: "history", usage: "history",
+		description: "Lists the recent edits made in the room you are in",
+		role:        ROLE_BUILDER,
+		run:         cmdHistory,
+	})
+	registerCommand(&command{
+		name: "prefabs", usage: "prefabs",
+		description: "Lists the prefabs that can be stamped into rooms",
+		role:        ROLE_BUILDER,
+		run:         cmdPrefabs,
+	})
+	registerCommand(&command{
+		name: "stamp", usage: "stamp <prefab> [quarter turns] [mirror]",
+		description: "Stamps a prefab into the room you are in with its first anchor where you stand",
+		minArgs:     1,
+		role:        ROLE_BUILDER,
+		run:         cmdStamp,
+	})
+	registerCommand(&command{
+		name: "revert", usage: "revert <revision> [room id]",
+		description: "Puts a room back the way it was at a revision, the room you are in by default",
+		minArgs:     1,
+		role:        ROLE_ADMIN,
+		run:         cmdRevert,
+	})
+	registerCommand(&command{
+		name: "mute", usage: "mute <player> <seconds>",
+		description: "Stops a player from chatting, 0 seconds lifts the mute",
+		minArgs:     2,
+		role:        ROLE_ADMIN,
+		run:         cmdMute,
+	})
+	registerCommand(&command{
+		name: "setrole", usage: "setrole <player> <role>",
+		description: "Changes the role of an online player to player, builder or admin",
+		minArgs:     2,
+		role:        ROLE_ADMIN,
+		run:         cmdSetRole,
+	})
+}
+
+// matchesName reports whether the query names the thing, either fully or by the start of one of its words
+func matchesName(name, query string) bool {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	if name == query || strings.HasPrefix(name, query) {
+		return true
+	}
+	for _, word := range strings.Fields(name) {
+		if strings.HasPrefix(word, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// findInventorySlot returns the first inventory slot holding an item matching the query
+func findInventorySlot(player *Player, query string) int {
+	for i, stack := range player.inventory.Slots {
+		template := ServerInstance.itemTemplates[stack.Template]
+		if stack.Template == query || (template != nil && matchesName(template.Name, query)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// slotItemName returns the name of the item in the inventory slot, items whose template is gone go by the template id
+func slotItemName(player *Player, slot int) string {
+	stack := player.inventory.Slots[slot]
+	if template := ServerInstance.itemTemplates[stack.Template]; template != nil {
+		return template.Name
+	}
+	return stack.Template
+}
+
+// nearestEntity returns the closest entity in the room accepted by the filter
+func nearestEntity(room *Room, from Vector2, filter func(e entity) bool) entity {
+	var best entity
+	for _, e := range room.entities {
+		if !filter(e) {
+			continue
+		}
+		if best == nil || distance(from, e.getPosition()) < distance(from, best.getPosition()) {
+			best = e
+		}
+	}
+	return best
+}
+
+// directionTo describes roughly where the target is as seen from the origin
+func directionTo(from, to Vector2) string {
+	dir := ""
+	switch {
+	case to.Y < from.Y:
+		dir = "north"
+	case to.Y > from.Y:
+		dir = "south"
+	}
+	switch {
+	case to.X > from.X:
+		dir += "east"
+	case to.X < from.X:
+		dir += "west"
+	}
+	if dir == "" {
+		return "here"
+	}
+	return dir
+}
+
+func cmdLook(ctx *commandContext, args []string) {
+	p := ctx.player
+	room := ServerInstance.FindRoom(p.currentRoom.String())
+	if room == nil {
+		ctx.reply("You are nowhere.")
+		return
+	}
+
+	visible := func(e entity) bool {
+		return e.getID() != p.id && p.canSee(room, e)
+	}
+
+	if len(args) > 0 {
+		query := joinArgs(args)
+		e := nearestEntity(room, p.position, func(e entity) bool {
+			return visible(e) && matchesName(e.getName(), query)
+		})
+		if e == nil {
+			ctx.reply("You don't see that here.")
+			return
+		}
+		ctx.reply("%s, %d steps %s.", e.getName(), distance(p.position, e.getPosition()), directionTo(p.position, e.getPosition()))
+		if e.getMaxHP() > 0 {
+			ctx.reply("Health: %d/%d", e.getCurrentHP(), e.getMaxHP())
+		}
+		return
+	}
+
+	// Text clients have no other way to see the room
+	if t := p.connection.telnet; t != nil {
+		ctx.output = append(ctx.output, asciiMap(room, p, t.width-2, t.height-10, t)...)
+	}
+	ctx.reply("%s", room.Name)
+	if room.Description != "" {
+		ctx.reply("%s", room.Description)
+	}
+	ctx.reply("The exit is %s of you.", directionTo(p.position, room.Exit.LocationInRoom))
+
+	seen := make([]entity, 0)
+	for _, e := range room.entities {
+		if visible(e) {
+			seen = append(seen, e)
+		}
+	}
+	sort.Slice(seen, func(i, j int) bool {
+		return distance(p.position, seen[i].getPosition()) < distance(p.position, seen[j].getPosition())
+	})
+	for _, e := range seen {
+		where := directionTo(p.position, e.getPosition())
+		if where == "here" {
+			ctx.reply("  %s is here.", e.getName())
+			continue
+		}
+		ctx.reply("  %s, %d steps %s.", e.getName(), distance(p.position, e.getPosition()), where)
+	}
+}
+
+func cmdMap(ctx *commandContext, args []string) {
+	p := ctx.player
+	room := ServerInstance.FindRoom(p.currentRoom.String())
+	if room == nil {
+		ctx.reply("You are nowhere.")
+		return
+	}
+	width, height := defaultMapWidth, defaultMapHeight
+	if t := p.connection.telnet; t != nil {
+		width, height = t.width-2, t.height-2
+	}
+	ctx.output = append(ctx.output, asciiMap(room, p, width, height, p.connection.telnet)...)
+}
+
+func cmdGo(ctx *commandContext, args []string) {
+	name := strings.ToLower(args[0])
+	if expanded, found := commandShortcut[name]; found && strings.HasPrefix(expanded, "go ") {
+		name = strings.TrimPrefix(expanded, "go ")
+	}
+
+	direction, found := directionNames[name]
+	if !found {
+		ctx.reply("You can't go that way.")
+		return
+	}
+	if direction >= 4 && !ServerInstance.config.DiagonalMovement {
+		ctx.reply("You can't move diagonally.")
+		return
+	}
+
+	if time.Now().Before(ctx.player.nextMove) {
+		ctx.reply("You need a moment before moving again.")
+		return
+	}
+	room := ctx.player.currentRoom
+	if !stepPlayer(ctx.player, moveDirections[direction]) {
+		ctx.reply("Something is in the way.")
+		return
+	}
+	if ctx.player.currentRoom != room {
+		cmdLook(ctx, nil)
+		return
+	}
+	ctx.reply("You walk %s.", name)
+}
+
+func cmdGet(ctx *commandContext, args []string) {
+	p := ctx.player
+	room := ServerInstance.FindRoom(p.currentRoom.String())
+	if room == nil {
+		ctx.reply(inventoryResultText[INVENTORY_ERROR_NOT_IN_WORLD])
+		return
+	}
+
+	query := joinArgs(args)
+	e := nearestEntity(room, p.position, func(e entity) bool {
+		item, ok := e.(*groundItem)
+		return ok && (item.stack.Template == query || matchesName(item.template.Name, query))
+	})
+	if e == nil {
+		ctx.reply(inventoryResultText[INVENTORY_ERROR_NOT_FOUND])
+		return
+	}
+
+	name := e.getName()
+	if result := pickUp(p, e.getID()); result != INVENTORY_OK {
+		ctx.reply(inventoryResultText[result])
+		return
+	}
+	ctx.reply("You pick up the %s.", strings.ToLower(name))
+}
+
+func cmdDrop(ctx *commandContext, args []string) {
+	quantity := 1
+	if len(args) > 1 {
+		if n, err := strconv.Atoi(args[len(args)-1]); err == nil {
+			quantity = n
+			args = args[:len(args)-1]
+		}
+	}
+
+	slot := findInventorySlot(ctx.player, joinArgs(args))
+	if slot < 0 {
+		ctx.reply("You aren't carrying that.")
+		return
+	}
+	name := slotItemName(ctx.player, slot)
+	if result := drop(ctx.player, slot, quantity); result != INVENTORY_OK {
+		ctx.reply(inventoryResultText[result])
+		return
+	}
+	ctx.reply("You drop the %s.", strings.ToLower(name))
+}
+
+func cmdUse(ctx *commandContext, args []string) {
+	slot := findInventorySlot(ctx.player, joinArgs(args))
+	if slot < 0 {
+		ctx.reply("You aren't carrying that.")
+		return
+	}
+	name := slotItemName(ctx.player, slot)
+	if result := useItem(ctx.player, slot); result != INVENTORY_OK {
+		ctx.reply(inventoryResultText[result])
+		return
+	}
+	ctx.reply("You use the %s.", strings.ToLower(name))
+}
+
+func cmdEquip(ctx *commandContext, args []string) {
+	slot := findInventorySlot(ctx.player, joinArgs(args))
+	if slot < 0 {
+		ctx.reply("You aren't carrying that.")
+		return
+	}
+	name := slotItemName(ctx.player, slot)
+	if result := equip(ctx.player, slot); result != INVENTORY_OK {
+		ctx.reply(inventoryResultText[result])
+		return
+	}
+	ctx.reply("You equip the %s.", strings.ToLower(name))
+}
+
+func cmdUnequip(ctx *commandContext, args []string) {
+	name := strings.ToLower(args[0])
+	for i, slot := range equipmentSlots {
+		if slot != name {
+			continue
+		}
+		if _, found := ctx.player.equipment[slot]; !found {
+			ctx.reply("You have nothing equipped there.")
+			return
+		}
+		if result := unequip(ctx.player, i); result != INVENTORY_OK {
+			ctx.reply(inventoryResultText[result])
+			return
+		}
+		ctx.reply("You take off your %s.", slot)
+		return
+	}
+	ctx.reply("There is no %s slot.", name)
+}
+
+func cmdInventory(ctx *commandContext, args []string) {
+	inv := ctx.player.inventory
+	if len(inv.Slots) == 0 {
+		ctx.reply("You are not carrying anything.")
+		return
+	}
+	ctx.reply("You are carrying (%d/%d weight):", inv.weight(), inventoryMaxWeight())
+	for _, stack := range inv.Slots {
+		name := stack.Template
+		if template, found := ServerInstance.itemTemplates[stack.Template]; found {
+			name = template.Name
+		}
+		if stack.Quantity > 1 {
+			ctx.reply("  %s x%d", name, stack.Quantity)
+			continue
+		}
+		ctx.reply("  %s", name)
+	}
+}
+
+func cmdScore(ctx *commandContext, args []string) {
+	p := ctx.player
+	ctx.reply("%s, level %d %s", p.name, p.level, roleNames[p.role])
+	if next := p.nextLevelExperience(); next > 0 {
+		ctx.reply("Experience: %d (next level at %d)", p.experience, next)
+	} else {
+		ctx.reply("Experience: %d (max level)", p.experience)
+	}
+	ctx.reply("Health: %d/%d", p.hp, p.maxhp)
+	a := p.derived.attributes
+	ctx.reply("Strength %d  Agility %d  Vitality %d  Perception %d", a.Strength, a.Agility, a.Vitality, a.Perception)
+	ctx.reply("Attack %d  Defence %d  Accuracy %d  Evasion %d  Crit %d%%",
+		p.stats.Attack, p.stats.Defence, p.stats.Accuracy, p.stats.Evasion, p.stats.CritChance)
+	for _, slot := range equipmentSlots {
+		if id, found := p.equipment[slot]; found {
+			name := id
+			if template, ok := ServerInstance.itemTemplates[id]; ok {
+				name = template.Name
+			}
+			ctx.reply("  %-8s %s", slot, name)
+		}
+	}
+}
+
+func cmdAttack(ctx *commandContext, args []string, attackType uint8) {
+	p := ctx.player
+	room := ServerInstance.FindRoom(p.currentRoom.String())
+	if room == nil {
+		ctx.reply(attackResultText[ATTACK_REJECTED_NOT_IN_WORLD])
+		return
+	}
+
+	query := joinArgs(args)
+	e := nearestEntity(room, p.position, func(e entity) bool {
+		_, ok := e.(*NPC)
+		return ok && matchesName(e.getName(), query)
+	})
+	if e == nil {
+		ctx.reply("You don't see that here.")
+		return
+	}
+	if result := attack(p, e.getID(), attackType); result != ATTACK_ACCEPTED {
+		ctx.reply(attackResultText[result])
+		return
+	}
+	if e.getCurrentHP() <= 0 {
+		ctx.reply("You killed the %s.", strings.ToLower(e.getName()))
+		return
+	}
+	ctx.reply("You attack the %s, it has %d/%d health left.", strings.ToLower(e.getName()), e.getCurrentHP(), e.getMaxHP())
+}
+
+func cmdChat(ctx *commandContext, channel uint8, target, message string) {
+	if result := sendChat(ctx.player, channel, target, message); result != CHAT_OK {
+		ctx.reply(chatResultText[result])
+	}
+}
+
+func cmdInvite(ctx *commandContext, args []string) {
+	target := ServerInstance.findPlayerByName(args[0])
+	if result := ctx.player.invite(target); result != CHAT_OK {
+		ctx.reply(chatResultText[result])
+		return
+	}
+	ctx.reply("You invite %s to your party.", target.name)
+}
+
+func cmdAccept(ctx *commandContext, args []string) {
+	if result := ctx.player.join(ServerInstance.findPlayerByName(args[0])); result != CHAT_OK {
+		ctx.reply("You have no invite from %s.", args[0])
+		return
+	}
+	ctx.reply("You join the party.")
+}
+
+func cmdWho(ctx *commandContext, args []string) {
+	players := ServerInstance.players()
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].name < players[j].name
+	})
+
+	ctx.reply("%d players online:", len(players))
+	for _, p := range players {
+		roomName := ""
+		if room := ServerInstance.FindRoom(p.currentRoom.String()); room != nil {
+			roomName = room.Name
+		}
+		ctx.reply("  %-16s level %-3d %s", p.name, p.level, roomName)
+	}
+}
+
+func cmdColour(ctx *commandContext, args []string) {
+	t := ctx.player.connection.telnet
+	if t == nil {
+		ctx.reply("Colours are only available to text clients.")
+		return
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		t.colour = true
+		ctx.reply("%s", t.paint(ansiGreen, "Colours are on."))
+	case "off":
+		t.colour = false
+		ctx.reply("Colours are off.")
+	default:
+		ctx.reply("Usage: colour <on|off>")
+	}
+}
+
+func cmdGoto(ctx *commandContext, args []string) {
+	p := ctx.player
+	room := ServerInstance.FindRoom(args[0])
+	if room == nil {
+		ctx.reply("There is no room %s.", args[0])
+		return
+	}
+	from := ServerInstance.FindRoom(p.currentRoom.String())
+	if from == nil || from == room {
+		ctx.reply("You are already there.")
+		return
+	}
+	if !room.fitsConnection(p.connection) {
+		ctx.reply("That room is too large for your client.")
+		return
+	}
+	transferPlayer(p, from, room)
+	cmdLook(ctx, nil)
+}
+
+func cmdMute(ctx *commandContext, args []string) {
+	target := ServerInstance.findPlayerByName(args[0])
+	if target == nil {
+		ctx.reply(chatResultText[CHAT_ERROR_NO_SUCH_PLAYER])
+		return
+	}
+	seconds, err := strconv.Atoi(args[1])
+	if err != nil || seconds < 0 {
+		ctx.reply("Usage: mute <player> <seconds>")
+		return
+	}
+
+	target.chat.mutedUntil = time.Now().Add(time.Duration(seconds) * time.Second)
+	if seconds == 0 {
+		ctx.reply("%s may talk again.", target.name)
+		return
+	}
+	ctx.reply("%s is muted for %d seconds.", target.name, seconds)
+}
+
+func cmdSetRole(ctx *commandContext, args []string) {
+	target := ServerInstance.findPlayerByName(args[0])
+	if target == nil {
+		ctx.reply(chatResultText[CHAT_ERROR_NO_SUCH_PLAYER])
+		return
+	}
+	for role, name := range roleNames {
+		if strings.EqualFold(name, args[1]) {
+			target.role = role
+			target.saveAccount()
+			ctx.reply("%s is now a %s.", target.name, name)
+			if role > ROLE_PLAYER {
+				ctx.reply("They need the %s token to join from now on.", name)
+			}
+			return
+		}
+	}
+	ctx.reply("There is no %s role.", args[1])
+}
+
+func cmdRoomHistory(ctx *commandContext, apply func(*Room, *Connection) (uint64, uint8)) {
+	room := ServerInstance.FindRoom(ctx.player.currentRoom.String())
+	if room == nil {
+		ctx.reply(roomUpdateResultText[ROOM_UPDATE_ERROR_UNKNOWN_ROOM])
+		return
+	}
+	revision, result := apply(room, ctx.player.connection)
+	if result != ROOM_UPDATE_OK {
+		ctx.reply(roomUpdateResultText[result])
+		return
+	}
+	ctx.reply("The room is now at revision %d.", revision)
+}
+
+func cmdHistory(ctx *commandContext, args []string) {
+	room := ServerInstance.FindRoom(ctx.player.currentRoom.String())
+	if room == nil {
+		ctx.reply(roomUpdateResultText[ROOM_UPDATE_ERROR_UNKNOWN_ROOM])
+		return
+	}
+	if len(room.history) == 0 {
+		ctx.reply("Nothing has been changed in this room.")
+		return
+	}
+
+	ctx.reply("Revision  Tiles  Author")
+	// The newest edits are the interesting ones
+	for i := len(room.history) - 1; i >= 0 && i >= len(room.history)-10; i-- {
+		edit := room.history[i]
+		author := edit.Author
+		if author == "" {
+			author = "server"
+		}
+		ctx.reply("%8d  %5d  %s", edit.Revision, len(edit.Tiles), author)
+	}
+	ctx.reply("%d edits can be undone and %d redone.", len(room.undo), len(room.redo))
+}
+
+func cmdRevert(ctx *commandContext, args []string) {
+	revision, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		ctx.reply("Usage: revert <revision> [room id]")
+		return
+	}
+	roomID := ctx.player.currentRoom.String()
+	if len(args) > 1 {
+		roomID = args[1]
+	}
+	room := ServerInstance.FindRoom(roomID)
+	if room == nil {
+		ctx.reply(roomUpdateResultText[ROOM_UPDATE_ERROR_UNKNOWN_ROOM])
+		return
+	}
+
+	applied, result := room.revertTo(revision, ctx.player.name)
+	if result != ROOM_UPDATE_OK {
+		ctx.reply(roomUpdateResultText[result])
+		return
+	}
+	ctx.reply("%s is back the way it was at revision %d, as revision %d.", room.Name, revision, applied)
+}
+
+func cmdPrefabs(ctx *commandContext, args []string) {
+	if len(ServerInstance.prefabs) == 0 {
+		ctx.reply("There are no prefabs.")
+		return
+	}
+	for _, id := range prefabIDs() {
+		p := ServerInstance.prefabs[id]
+		ctx.reply("%-16s %3dx%-3d %s", p.ID, p.Width, p.Height, p.Name)
+	}
+}
+
+func cmdStamp(ctx *commandContext, args []string) {
+	rotation, mirror := 0, false
+	if len(args) > 1 {
+		turns, err := strconv.Atoi(args[1])
+		if err != nil || turns < 0 {
+			ctx.reply("Usage: stamp <prefab> [quarter turns] [mirror]")
+			return
+		}
+		rotation = turns
+	}
+	if len(args) > 2 {
+		mirror = args[2] == "mirror"
+	}
+	room := ServerInstance.FindRoom(ctx.player.currentRoom.String())
+	if room == nil {
+		ctx.reply(roomUpdateResultText[ROOM_UPDATE_ERROR_UNKNOWN_ROOM])
+		return
+	}
+
+	result, pos := room.stampPrefab(ctx.player.connection, args[0], ctx.player.position, 0, rotation, mirror)
+	if result != ROOM_UPDATE_OK {
+		ctx.reply("%s (%d, %d)", roomUpdateResultText[result], pos.X, pos.Y)
+		return
+	}
+	ctx.reply("Stamped %s, the room is now at revision %d.", args[0], room.revision)
+}
@@ -0,0 +1,112 @@
+package game
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	COMPRESSION_NONE = uint8(iota)
+	COMPRESSION_FLATE
+)
+
+// frameFlagCompressed is set in the length prefix of frames compressed with flate. Frames can never be
+// anywhere near 2 GB so the top bit of the length is free to use.
+const frameFlagCompressed = uint32(1) << 31
+
+const defaultCompressionThreshold = 1024
+
+// CompressingTransport is a transport that can compress big frames
+type CompressingTransport interface {
+	Transport
+	EnableCompression(threshold int)
+}
+
+// EnableCompression compresses every frame written from now on that is bigger than the threshold
+func (t *streamTransport) EnableCompression(threshold int) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	t.compressThreshold = threshold
+}
+
+// compressFrame deflates the frame, the compressor is reused as setting one up is expensive
+func (t *streamTransport) compressFrame(frame []byte) ([]byte, error) {
+	t.compressBuf.Reset()
+	if t.compressor == nil {
+		w, err := flate.NewWriter(&t.compressBuf, flate.BestSpeed)
+		if err != nil {
+			return nil, err
+		}
+		t.compressor = w
+	} else {
+		t.compressor.Reset(&t.compressBuf)
+	}
+
+	if _, err := t.compressor.Write(frame); err != nil {
+		return nil, err
+	}
+	if err := t.compressor.Close(); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), t.compressBuf.Bytes()...), nil
+}
+
+// decompressFrame inflates a frame sent by the client, a frame may not inflate past maxFrameLength
+func decompressFrame(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	frame, err := ioutil.ReadAll(io.LimitReader(r, maxFrameLength+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(frame) > maxFrameLength {
+		return nil, ErrFrameTooLarge
+	}
+	return frame, nil
+}
+
+type CompressionHandler struct{}
+
+/*
+*****************************
+COMPRESSION REQUEST STRUCTURE
+*****************************
+1 byte - algorithm (0 - none, 1 - flate)
+
+Response:
+1 byte - algorithm in use from now on
+4 bytes - uint32 frames bigger than this many bytes are compressed
+
+Compressed frames have the top bit of the length prefix set, the length is that of the compressed data.
+The response itself is never compressed. Clients may compress the frames they send once it's enabled.
+*/
+func (h CompressionHandler) handle(packet *Packet) {
+	algorithm := packet.ReadUint8()
+
+	conf := ServerInstance.config.Compression
+	threshold := conf.Threshold
+	if threshold <= 0 {
+		threshold = defaultCompressionThreshold
+	}
+
+	transport, ok := packet.Connection.transport.(CompressingTransport)
+	if !ok || !conf.Enabled || algorithm != COMPRESSION_FLATE {
+		algorithm = COMPRESSION_NONE
+	}
+
+	response := NewPacket(MsgCompressionResponse)
+	response.WriteUint8(algorithm)
+	response.WriteUint32(uint32(threshold))
+	sendMessageToConnection(packet.Connection, *response)
+
+	if ok {
+		if algorithm == COMPRESSION_FLATE {
+			transport.EnableCompression(threshold)
+		} else {
+			transport.EnableCompression(0)
+		}
+	}
+}
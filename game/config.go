@@ -5,7 +5,51 @@ type serverConfig struct {
 	ServerPort      int    `json:"server_port"`
 	ServerAddress   string `json:"server_address"`
 	PingConnections bool   `json:"ping_connections"`
-	RoomData        struct {
+	// TelnetPort accepts plain text connections from MUD clients, 0 disables the telnet listener
+	TelnetPort int `json:"telnet_port"`
+	// TLS encrypts the game listener, a self signed certificate is created in the config directory when asked for
+	TLS struct {
+		Enabled    bool   `json:"enabled"`
+		CertFile   string `json:"cert_file"`
+		KeyFile    string `json:"key_file"`
+		MinVersion string `json:"min_version"`
+		SelfSigned bool   `json:"self_signed"`
+	} `json:"tls"`
+	// Compression lets clients ask for frames bigger than Threshold bytes to be compressed
+	Compression struct {
+		Enabled   bool `json:"enabled"`
+		Threshold int  `json:"threshold"`
+	} `json:"compression"`
+	// WebSocket serves browser clients over HTTP, a port of 0 disables it
+	WebSocket struct {
+		Port           int      `json:"port"`
+		Path           string   `json:"path"`
+		AllowedOrigins []string `json:"allowed_origins"`
+	} `json:"websocket"`
+	// DiagonalMovement allows players and npcs to move in 8 directions instead of 4
+	DiagonalMovement bool `json:"diagonal_movement"`
+	// FogOfWar only sends players the tiles they have seen, ViewRadius limits how far they can see
+	FogOfWar   bool `json:"fog_of_war"`
+	ViewRadius int  `json:"view_radius"`
+	// CombatSeed seeds the combat dice, 0 picks a random seed on every start
+	CombatSeed int64 `json:"combat_seed"`
+	// Inventory limits for every player
+	InventorySlots     int `json:"inventory_slots"`
+	InventoryMaxWeight int `json:"inventory_max_weight"`
+	Chat               struct {
+		MaxMessageLength  int      `json:"max_message_length"`
+		RateLimitMessages int      `json:"rate_limit_messages"`
+		RateLimitSeconds  int      `json:"rate_limit_seconds"`
+		SpamMuteSeconds   int      `json:"spam_mute_seconds"`
+		Profanity         []string `json:"profanity"`
+	} `json:"chat"`
+	// Admins get the admin role when they join with AdminToken, use them to hand out roles in game.
+	// Admins, including those made admin in game, can't join without the token and builders can't join
+	// without BuilderToken or AdminToken.
+	Admins       []string `json:"admins"`
+	AdminToken   string   `json:"admin_token"`
+	BuilderToken string   `json:"builder_token"`
+	RoomData     struct {
 		Config struct {
 			MinWidth  int `json:"min_width"`
 			MaxWidth  int `json:"max_width"`
@@ -13,5 +57,7 @@ type serverConfig struct {
 			MaxHeight int `json:"maxHeight"`
 		} `json:"config"`
 		MinRooms int `json:"min_rooms"`
+		// Prefabs is how many prefabs the generator tries to place in every new room
+		Prefabs int `json:"prefabs"`
 	} `json:"room_data"`
 }
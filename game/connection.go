@@ -2,58 +2,56 @@ package game
 
 import (
 	"fmt"
-	"net"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type (
 	Connection struct {
-		conn          net.Conn
-		timeConnected time.Time
-		player        *Player
-		isEditor      bool
+		transport       Transport
+		timeConnected   time.Time
+		player          *Player
+		isEditor        bool
+		protocolVersion uint16
+		telnet          *telnetSession
+		// Rooms an editor asked to be kept up to date on without standing in them
+		watching map[uuid.UUID]bool
 	}
 )
 
-// listen goroutine listens for incoming data
+// listen goroutine listens for incoming frames
 func (connection *Connection) listen() {
-	recvBuf := make([]byte, 1024*1024)
-	packet := &Packet{
-		buffer:     make([]byte, 0),
-		cursor:     0,
-		Connection: connection,
-	}
-
 	for {
-		cLen, err := connection.conn.Read(recvBuf)
+		frame, err := connection.transport.ReadFrame()
 		if err != nil {
 			// client disconnected
-			connection.conn.Close()
+			connection.transport.Close()
 			ServerInstance.onClientConnectionClosed(connection, err)
 			return
 		}
-		// Need now to copy the read bytes amount and process it
-		//processMessage(connection, recvBuf, len)
-
-		temp := make([]byte, cLen)
-		temp = recvBuf[:cLen]
-		packet.Reset(handleData(temp, packet))
+		handleFrame(connection, frame)
 	}
 
 }
 
 func sendMessageToConnection(connection *Connection, packet Packet) {
+	if connection.telnet != nil {
+		connection.render(packet)
+		return
+	}
 
-	bytes, err := connection.conn.Write(packet.GetBytes())
+	frame := packet.GetFrame()
+	err := connection.transport.WriteFrame(frame)
 	if err == nil {
-		fmt.Println(fmt.Sprintf("Written %d bytes to stream", bytes))
+		fmt.Println(fmt.Sprintf("Written %d bytes to stream", len(frame)+4))
 	} else {
 		fmt.Println(err)
 	}
 }
 
 func (connection *Connection) sendBytes(data []byte) {
-	connection.conn.Write(data)
+	connection.transport.WriteFrame(data)
 }
 func (connection *Connection) sendString(data string) {
 	connection.sendBytes([]byte(data))
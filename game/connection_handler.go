@@ -1,47 +1,37 @@
 package game
 
-import "fmt"
+import (
+	"fmt"
 
-func handleData(data []byte, receivedData *Packet) bool {
-	packetLength := uint32(0)
-	receivedData.SetBytes(data)
+	"github.com/rs/zerolog/log"
+)
 
-	if receivedData.UnreadLength() >= 4 {
-		packetLength = receivedData.ReadUInt32()
-		if packetLength <= 0 {
-			return true
-		}
+// handleFrame runs the handler registered for the packet type of a single frame
+func handleFrame(connection *Connection, frame []byte) {
+	if len(frame) < 2 {
+		fmt.Println("Received a frame without a packet type")
+		return
 	}
 
-	for packetLength > 0 && packetLength <= receivedData.UnreadLength() {
-		packetBytes := receivedData.ReadBytes(packetLength)
-
-		//TODO: Maybe look into goroutine
-		newPaket := NewUnknownPacket(packetBytes)
-		newPaket.Connection = receivedData.Connection
-		fmt.Println(fmt.Sprintf("Total handlers: %d", len(ServerInstance.packetHandler)))
-
-		t := newPaket.GetMessageType()
-		_, ok := ServerInstance.packetHandler[t]
-
-		if !ok {
-			fmt.Println(fmt.Sprintf("There is no handler registered for packet type %d", t))
-		}
+	packet := NewUnknownPacket(frame)
+	packet.Connection = connection
+	fmt.Println(fmt.Sprintf("Total handlers: %d", len(ServerInstance.packetHandler)))
 
-		ServerInstance.packetHandler[t].handle(newPaket)
-
-		packetLength = 0
-		if receivedData.UnreadLength() >= 4 {
-			packetLength = receivedData.UnreadLength()
-			if packetLength <= 0 {
-				return true
-			}
-		}
+	t := packet.GetMessageType()
+	handler, ok := ServerInstance.packetHandler[t]
+	if !ok {
+		fmt.Println(fmt.Sprintf("There is no handler registered for packet type %d", t))
+		return
 	}
 
-	if packetLength <= 1 {
-		return true
-	}
+	ServerInstance.mu.Lock()
+	defer ServerInstance.mu.Unlock()
 
-	return false
+	// A malformed packet makes the readers run past the end of the buffer, that must not take the server down
+	defer func() {
+		if r := recover(); r != nil {
+			log.Warn().Str("error", fmt.Sprint(r)).Uint16("type", uint16(t)).Msg("Failed to handle packet")
+		}
+	}()
+	handler.handle(packet)
 }
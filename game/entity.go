@@ -1,12 +1,26 @@
 package game
 
+import (
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
 type entity interface {
 	getName() string
 	getID() int64
 	isPlayer() bool
 	isInteractable() bool
 	ispassable() bool
-	getRoomID() int64
+	getRoomID() uuid.UUID
+	getPosition() Vector2
 	getCurrentHP() int
 	getMaxHP() int
 }
+
+var lastEntityID int64
+
+// nextEntityID returns a unique id shared between players and non player entities
+func nextEntityID() int64 {
+	return atomic.AddInt64(&lastEntityID, 1)
+}
@@ -0,0 +1,87 @@
+package game
+
+const (
+	EQUIPMENT_SLOT_WEAPON  = "weapon"
+	EQUIPMENT_SLOT_OFFHAND = "offhand"
+	EQUIPMENT_SLOT_HEAD    = "head"
+	EQUIPMENT_SLOT_BODY    = "body"
+	EQUIPMENT_SLOT_LEGS    = "legs"
+	EQUIPMENT_SLOT_FEET    = "feet"
+	EQUIPMENT_SLOT_RING    = "ring"
+)
+
+// equipmentSlots is the order in which slots are sent over the network
+var equipmentSlots = []string{
+	EQUIPMENT_SLOT_WEAPON,
+	EQUIPMENT_SLOT_OFFHAND,
+	EQUIPMENT_SLOT_HEAD,
+	EQUIPMENT_SLOT_BODY,
+	EQUIPMENT_SLOT_LEGS,
+	EQUIPMENT_SLOT_FEET,
+	EQUIPMENT_SLOT_RING,
+}
+
+func isEquipmentSlot(slot string) bool {
+	for _, s := range equipmentSlots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// equip moves an item from the inventory into its equipment slot, whatever was there goes back to the inventory
+func equip(player *Player, inventorySlot int) uint8 {
+	if player == nil {
+		return INVENTORY_ERROR_NOT_IN_WORLD
+	}
+	if inventorySlot < 0 || inventorySlot >= len(player.inventory.Slots) {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+
+	template := ServerInstance.itemTemplates[player.inventory.Slots[inventorySlot].Template]
+	if template == nil || !isEquipmentSlot(template.Slot) {
+		return INVENTORY_ERROR_NOT_EQUIPPABLE
+	}
+
+	stack, _ := player.inventory.take(inventorySlot, 1)
+	if previous, found := player.equipment[template.Slot]; found {
+		swapped := itemStack{Template: previous, Quantity: 1}
+		if reason := player.inventory.canAdd(swapped); reason != INVENTORY_OK {
+			player.inventory.add(stack)
+			return reason
+		}
+		player.inventory.add(swapped)
+	}
+	player.equipment[template.Slot] = stack.Template
+
+	player.recalculateStats()
+	player.onInventoryChanged()
+	return INVENTORY_OK
+}
+
+// unequip moves the item in the given slot back into the inventory
+func unequip(player *Player, slot int) uint8 {
+	if player == nil {
+		return INVENTORY_ERROR_NOT_IN_WORLD
+	}
+	if slot < 0 || slot >= len(equipmentSlots) {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+
+	id, found := player.equipment[equipmentSlots[slot]]
+	if !found {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+
+	stack := itemStack{Template: id, Quantity: 1}
+	if reason := player.inventory.canAdd(stack); reason != INVENTORY_OK {
+		return reason
+	}
+	delete(player.equipment, equipmentSlots[slot])
+	player.inventory.add(stack)
+
+	player.recalculateStats()
+	player.onInventoryChanged()
+	return INVENTORY_OK
+}
@@ -0,0 +1,27 @@
+package game
+
+type EquipHandler struct{}
+
+/*
+*****************************
+EQUIP REQUEST STRUCTURE
+*****************************
+1 byte - inventory slot
+*/
+func (h EquipHandler) handle(packet *Packet) {
+	slot := int(packet.ReadUint8())
+	sendInventoryResult(packet.Connection, equip(packet.Connection.player, slot))
+}
+
+type UnequipHandler struct{}
+
+/*
+*****************************
+UNEQUIP REQUEST STRUCTURE
+*****************************
+1 byte - equipment slot index, see equipmentSlots
+*/
+func (h UnequipHandler) handle(packet *Packet) {
+	slot := int(packet.ReadUint8())
+	sendInventoryResult(packet.Connection, unequip(packet.Connection.player, slot))
+}
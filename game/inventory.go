@@ -0,0 +1,162 @@
+package game
+
+const (
+	defaultInventorySlots  = 20
+	defaultInventoryWeight = 100
+)
+
+const (
+	INVENTORY_OK = uint8(iota)
+	INVENTORY_ERROR_NOT_IN_WORLD
+	INVENTORY_ERROR_NOT_FOUND
+	INVENTORY_ERROR_TOO_FAR
+	INVENTORY_ERROR_FULL
+	INVENTORY_ERROR_TOO_HEAVY
+	INVENTORY_ERROR_NOT_USABLE
+	INVENTORY_ERROR_NOT_EQUIPPABLE
+)
+
+// itemStack is a number of items of the same template
+type itemStack struct {
+	Template string `json:"template"`
+	Quantity int    `json:"quantity"`
+}
+
+// Inventory holds the items a player carries
+type Inventory struct {
+	Slots []*itemStack `json:"slots"`
+}
+
+func NewInventory() *Inventory {
+	return &Inventory{
+		Slots: make([]*itemStack, 0),
+	}
+}
+
+func inventorySlots() int {
+	if ServerInstance.config.InventorySlots > 0 {
+		return ServerInstance.config.InventorySlots
+	}
+	return defaultInventorySlots
+}
+
+func inventoryMaxWeight() int {
+	if ServerInstance.config.InventoryMaxWeight > 0 {
+		return ServerInstance.config.InventoryMaxWeight
+	}
+	return defaultInventoryWeight
+}
+
+// weight returns the combined weight of every carried item
+func (inv *Inventory) weight() int {
+	total := 0
+	for _, s := range inv.Slots {
+		if t, found := ServerInstance.itemTemplates[s.Template]; found {
+			total += t.Weight * s.Quantity
+		}
+	}
+	return total
+}
+
+// canAdd checks the slot and weight limits for the given stack
+func (inv *Inventory) canAdd(stack itemStack) uint8 {
+	template, found := ServerInstance.itemTemplates[stack.Template]
+	if !found {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+	if inv.weight()+template.Weight*stack.Quantity > inventoryMaxWeight() {
+		return INVENTORY_ERROR_TOO_HEAVY
+	}
+
+	// Count how many new slots we would need after topping up existing stacks
+	left := stack.Quantity
+	for _, s := range inv.Slots {
+		if s.Template == stack.Template && s.Quantity < template.MaxStack {
+			left -= template.MaxStack - s.Quantity
+		}
+	}
+	needed := 0
+	if left > 0 {
+		needed = (left + template.MaxStack - 1) / template.MaxStack
+	}
+	if len(inv.Slots)+needed > inventorySlots() {
+		return INVENTORY_ERROR_FULL
+	}
+	return INVENTORY_OK
+}
+
+// add puts the stack into the inventory, it must be checked with canAdd first
+func (inv *Inventory) add(stack itemStack) {
+	template := ServerInstance.itemTemplates[stack.Template]
+	left := stack.Quantity
+
+	for _, s := range inv.Slots {
+		if left == 0 {
+			return
+		}
+		if s.Template == stack.Template && s.Quantity < template.MaxStack {
+			moved := template.MaxStack - s.Quantity
+			if moved > left {
+				moved = left
+			}
+			s.Quantity += moved
+			left -= moved
+		}
+	}
+
+	for left > 0 {
+		moved := left
+		if moved > template.MaxStack {
+			moved = template.MaxStack
+		}
+		inv.Slots = append(inv.Slots, &itemStack{Template: stack.Template, Quantity: moved})
+		left -= moved
+	}
+}
+
+// take removes up to quantity items from the slot and returns what was removed
+func (inv *Inventory) take(slot, quantity int) (itemStack, bool) {
+	if slot < 0 || slot >= len(inv.Slots) || quantity <= 0 {
+		return itemStack{}, false
+	}
+
+	s := inv.Slots[slot]
+	if quantity > s.Quantity {
+		quantity = s.Quantity
+	}
+	s.Quantity -= quantity
+	if s.Quantity == 0 {
+		inv.Slots = append(inv.Slots[:slot], inv.Slots[slot+1:]...)
+	}
+	return itemStack{Template: s.Template, Quantity: quantity}, true
+}
+
+/*
+sendInventory sends the full inventory to the player
+
+*****************************
+INVENTORY STRUCTURE
+*****************************
+1 byte - number of used slots
+1 byte - max number of slots
+2 bytes - uint16 carried weight
+2 bytes - uint16 max weight
+... 2 bytes + <n> bytes - item template id
+... 2 bytes - uint16 quantity
+*/
+func (p *Player) sendInventory() {
+	if p.connection == nil {
+		return
+	}
+
+	pkt := NewPacket(MsgInventory)
+	pkt.WriteIntByte(len(p.inventory.Slots))
+	pkt.WriteIntByte(inventorySlots())
+	pkt.WriteUint16(uint16(p.inventory.weight()))
+	pkt.WriteUint16(uint16(inventoryMaxWeight()))
+	for _, s := range p.inventory.Slots {
+		pkt.WriteString(s.Template)
+		pkt.WriteUint16(uint16(s.Quantity))
+	}
+	sendMessageToConnection(p.connection, *pkt)
+}
@@ -0,0 +1,136 @@
+package game
+
+type PickUpHandler struct{}
+
+/*
+*****************************
+PICK UP REQUEST STRUCTURE
+*****************************
+8 bytes - uint64 item entity id, the item has to be on the same or a neighbouring tile
+*/
+func (h PickUpHandler) handle(packet *Packet) {
+	itemID := int64(packet.ReadUint64())
+	player := packet.Connection.player
+	sendInventoryResult(packet.Connection, pickUp(player, itemID))
+}
+
+func pickUp(player *Player, itemID int64) uint8 {
+	if player == nil {
+		return INVENTORY_ERROR_NOT_IN_WORLD
+	}
+	room := ServerInstance.FindRoom(player.currentRoom.String())
+	if room == nil {
+		return INVENTORY_ERROR_NOT_IN_WORLD
+	}
+
+	item, ok := room.entities[itemID].(*groundItem)
+	if !ok {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+	if distance(player.position, item.position) > 1 {
+		return INVENTORY_ERROR_TOO_FAR
+	}
+	if reason := player.inventory.canAdd(item.stack); reason != INVENTORY_OK {
+		return reason
+	}
+
+	room.removeEntity(item, DESPAWN_REASON_REMOVED)
+	player.inventory.add(item.stack)
+	player.onInventoryChanged()
+	return INVENTORY_OK
+}
+
+type DropHandler struct{}
+
+/*
+*****************************
+DROP REQUEST STRUCTURE
+*****************************
+1 byte - inventory slot
+2 bytes - uint16 quantity
+*/
+func (h DropHandler) handle(packet *Packet) {
+	slot := int(packet.ReadUint8())
+	quantity := packet.ReadUint16AsInt()
+	sendInventoryResult(packet.Connection, drop(packet.Connection.player, slot, quantity))
+}
+
+func drop(player *Player, slot, quantity int) uint8 {
+	if player == nil {
+		return INVENTORY_ERROR_NOT_IN_WORLD
+	}
+	room := ServerInstance.FindRoom(player.currentRoom.String())
+	if room == nil {
+		return INVENTORY_ERROR_NOT_IN_WORLD
+	}
+
+	// Items whose template is gone can't be placed on the ground, keep them rather than lose them
+	if slot < 0 || slot >= len(player.inventory.Slots) {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+	if _, found := ServerInstance.itemTemplates[player.inventory.Slots[slot].Template]; !found {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+
+	stack, ok := player.inventory.take(slot, quantity)
+	if !ok {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+	dropItem(room, stack, player.position)
+	player.onInventoryChanged()
+	return INVENTORY_OK
+}
+
+type UseItemHandler struct{}
+
+/*
+*****************************
+USE ITEM REQUEST STRUCTURE
+*****************************
+1 byte - inventory slot
+*/
+func (h UseItemHandler) handle(packet *Packet) {
+	slot := int(packet.ReadUint8())
+	sendInventoryResult(packet.Connection, useItem(packet.Connection.player, slot))
+}
+
+func useItem(player *Player, slot int) uint8 {
+	if player == nil {
+		return INVENTORY_ERROR_NOT_IN_WORLD
+	}
+	if slot < 0 || slot >= len(player.inventory.Slots) {
+		return INVENTORY_ERROR_NOT_FOUND
+	}
+
+	template := ServerInstance.itemTemplates[player.inventory.Slots[slot].Template]
+	if template == nil || template.Kind != ITEM_KIND_CONSUMABLE {
+		return INVENTORY_ERROR_NOT_USABLE
+	}
+
+	player.inventory.take(slot, 1)
+	player.hp += template.Heal
+	if player.hp > player.maxhp {
+		player.hp = player.maxhp
+	}
+	player.sendCharacterSheet()
+	player.onInventoryChanged()
+	return INVENTORY_OK
+}
+
+// onInventoryChanged sends the new inventory to the player and persists it
+func (p *Player) onInventoryChanged() {
+	p.sendInventory()
+	p.saveAccount()
+}
+
+/*
+*****************************
+INVENTORY RESULT STRUCTURE
+*****************************
+1 byte - result (0 - ok, anything else is an error)
+*/
+func sendInventoryResult(connection *Connection, result uint8) {
+	pkt := NewPacket(MsgInventoryResult)
+	pkt.WriteUint8(result)
+	sendMessageToConnection(connection, *pkt)
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ITEM_KIND_WEAPON     = "weapon"
+	ITEM_KIND_ARMOUR     = "armour"
+	ITEM_KIND_CONSUMABLE = "consumable"
+	ITEM_KIND_KEY        = "key"
+)
+
+// itemTemplate describes an item as defined in the item data file
+type itemTemplate struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Weight   int    `json:"weight"`
+	MaxStack int    `json:"max_stack"`
+	Value    int    `json:"value"`
+	Heal     int    `json:"heal"`
+	// Slot is the equipment slot the item goes into, items without one can't be equipped
+	Slot      string      `json:"slot"`
+	Modifiers attributes  `json:"modifiers"`
+	Bonuses   combatStats `json:"bonuses"`
+}
+
+// groundItem is a stack of items lying on a room tile
+type groundItem struct {
+	id          int64
+	stack       itemStack
+	template    *itemTemplate
+	currentRoom uuid.UUID
+	position    Vector2
+}
+
+func newGroundItem(stack itemStack, template *itemTemplate, room *Room, position Vector2) *groundItem {
+	return &groundItem{
+		id:          nextEntityID(),
+		stack:       stack,
+		template:    template,
+		currentRoom: room.ID,
+		position:    position,
+	}
+}
+
+func (i *groundItem) ispassable() bool {
+	return true
+}
+
+func (i *groundItem) getName() string {
+	return i.template.Name
+}
+
+func (i *groundItem) getID() int64 {
+	return i.id
+}
+
+func (i *groundItem) isPlayer() bool {
+	return false
+}
+
+func (i *groundItem) isInteractable() bool {
+	return true
+}
+
+func (i *groundItem) getRoomID() uuid.UUID {
+	return i.currentRoom
+}
+
+func (i *groundItem) getPosition() Vector2 {
+	return i.position
+}
+
+func (i *groundItem) getCurrentHP() int {
+	return 0
+}
+
+func (i *groundItem) getMaxHP() int {
+	return 0
+}
+
+// dropItem places a stack of items on the given room tile
+func dropItem(room *Room, stack itemStack, position Vector2) *groundItem {
+	template, found := ServerInstance.itemTemplates[stack.Template]
+	if !found {
+		log.Warn().Str("template", stack.Template).Msg("Attempted to drop an unknown item")
+		return nil
+	}
+
+	item := newGroundItem(stack, template, room, position)
+	room.addEntity(item)
+	return item
+}
+
+// loadItemTemplates reads item definitions from the data directory, creating a default file if there is none
+func loadItemTemplates(dataPath string) (map[string]*itemTemplate, error) {
+	filePath := path.Join(dataPath, "items.json")
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		log.Info().Str("filepath", filePath).Msg("Item data file does not exist, creating a new one")
+		defaults := []itemTemplate{
+			{
+				ID: "rusty_sword", Name: "Rusty sword", Kind: ITEM_KIND_WEAPON, Weight: 6, MaxStack: 1, Value: 10,
+				Slot: EQUIPMENT_SLOT_WEAPON, Modifiers: attributes{Strength: 1}, Bonuses: combatStats{Attack: 4},
+			},
+			{
+				ID: "short_bow", Name: "Short bow", Kind: ITEM_KIND_WEAPON, Weight: 4, MaxStack: 1, Value: 15,
+				Slot: EQUIPMENT_SLOT_WEAPON, Modifiers: attributes{Perception: 2}, Bonuses: combatStats{Attack: 2, Accuracy: 5, AttackRange: rangedRange},
+			},
+			{
+				ID: "leather_armour", Name: "Leather armour", Kind: ITEM_KIND_ARMOUR, Weight: 10, MaxStack: 1, Value: 20,
+				Slot: EQUIPMENT_SLOT_BODY, Modifiers: attributes{Vitality: 2, Agility: -1}, Bonuses: combatStats{Defence: 4},
+			},
+			{ID: "healing_potion", Name: "Healing potion", Kind: ITEM_KIND_CONSUMABLE, Weight: 1, MaxStack: 10, Value: 5, Heal: 25},
+			{ID: "iron_key", Name: "Iron key", Kind: ITEM_KIND_KEY, Weight: 0, MaxStack: 1},
+		}
+		jData, _ := json.MarshalIndent(defaults, "", " ")
+		if err := ioutil.WriteFile(filePath, jData, 0666); err != nil {
+			log.Warn().Err(err).Msg("Failed to write item data file to disk")
+		}
+	}
+
+	fData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read item data file from disk")
+		return nil, err
+	}
+
+	list := make([]*itemTemplate, 0)
+	if err := json.Unmarshal(fData, &list); err != nil {
+		log.Warn().Err(err).Msg("failed to unmarshal item data")
+		return nil, err
+	}
+
+	templates := make(map[string]*itemTemplate, len(list))
+	for _, t := range list {
+		if t.MaxStack < 1 {
+			t.MaxStack = 1
+		}
+		templates[t.ID] = t
+	}
+	log.Debug().Int("count", len(templates)).Msg("Loaded item templates")
+	return templates, nil
+}
@@ -11,9 +11,68 @@ const (
 	MsgSpecial2
 	MsgRoomCountRequest
 	MsgRoomCountResponse
+	MsgCompressionRequest   PacketType = 10
+	MsgCompressionResponse  PacketType = 11
+	MsgVersionRequest       PacketType = 12
+	MsgVersionResponse      PacketType = 13
+	MsgPlayerJoinRequest    PacketType = 100
+	MsgPlayerJoinResponse   PacketType = 101
+	MsgPlayerMoveRequest    PacketType = 102
+	MsgPlayerMoveToRequest  PacketType = 103
+	MsgPlayerMoveToResponse PacketType = 104
+	MsgPlayerFieldOfView    PacketType = 105
+	MsgRoomChunk            PacketType = 106
+	MsgRoomChunkUnload      PacketType = 107
+	MsgTileDefinitions      PacketType = 108
+	MsgEntitySpawn          PacketType = 200
+	MsgEntityDespawn        PacketType = 201
+	MsgEntityMove           PacketType = 202
+	MsgAttackRequest        PacketType = 300
+	MsgCombatEvent          PacketType = 301
+	MsgAttackRejected       PacketType = 302
+	MsgInventory            PacketType = 400
+	MsgPickUpRequest        PacketType = 401
+	MsgDropRequest          PacketType = 402
+	MsgUseItemRequest       PacketType = 403
+	MsgInventoryResult      PacketType = 404
+	MsgEquipRequest         PacketType = 405
+	MsgUnequipRequest       PacketType = 406
+	MsgCharacterSheet       PacketType = 407
+	MsgExperienceGain       PacketType = 408
+	MsgLevelUp              PacketType = 409
+	MsgChatRequest          PacketType = 500
+	MsgChatMessage          PacketType = 501
+	MsgChatError            PacketType = 502
+	MsgChatIgnoreRequest    PacketType = 503
+	MsgChatMuteRequest      PacketType = 504
+	MsgPartyInviteRequest   PacketType = 505
+	MsgPartyInvite          PacketType = 506
+	MsgPartyAcceptRequest   PacketType = 507
+	MsgPartyLeaveRequest    PacketType = 508
+	MsgPartyUpdate          PacketType = 509
+	MsgCommandRequest       PacketType = 600
+	MsgCommandOutput        PacketType = 601
 	MsgRoomUpdateName       PacketType = 1000
 	MsgUpdateRoomPayload    PacketType = 1001
 	MsgUpdateRoomPayloadAck PacketType = 1002
 	MsgRoomUpdateStatus     PacketType = 1003
 	MsgRoomMiscUpdate       PacketType = 1004
+	MsgRoomTileDelta        PacketType = 1005
+	MsgRoomResyncRequest    PacketType = 1006
+	MsgRoomUpdateResult     PacketType = 1007
+	MsgRoomCreateRequest    PacketType = 1008
+	MsgRoomCloneRequest     PacketType = 1009
+	MsgRoomDeleteRequest    PacketType = 1010
+	MsgRoomRemoved          PacketType = 1011
+	MsgRoomUndoRequest      PacketType = 1012
+	MsgRoomRedoRequest      PacketType = 1013
+	MsgRoomHistory          PacketType = 1014
+	MsgRoomLockRequest      PacketType = 1015
+	MsgRoomPresence         PacketType = 1016
+	MsgRoomEditConflict     PacketType = 1017
+	MsgRoomEditRejected     PacketType = 1018
+	MsgPrefabSaveRequest    PacketType = 1019
+	MsgPrefabStampRequest   PacketType = 1020
+	MsgPrefabListRequest    PacketType = 1021
+	MsgPrefabList           PacketType = 1022
 )
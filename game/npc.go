@@ -0,0 +1,149 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	NPC_KIND_MONSTER = "monster"
+	NPC_KIND_NPC     = "npc"
+)
+
+// npcTemplate describes a non player entity as defined in the npc data file
+type npcTemplate struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Kind         string       `json:"kind"`
+	MaxHP        int          `json:"max_hp"`
+	Interactable bool         `json:"interactable"`
+	Behaviour    npcBehaviour `json:"behaviour"`
+	Stats        combatStats  `json:"stats"`
+	Loot         []lootEntry  `json:"loot"`
+	Experience   int          `json:"experience"`
+}
+
+// lootEntry is an item an npc can drop when it dies, chance is in percent
+type lootEntry struct {
+	Item     string `json:"item"`
+	Chance   int    `json:"chance"`
+	Quantity int    `json:"quantity"`
+}
+
+type NPC struct {
+	id          int64
+	template    *npcTemplate
+	currentRoom uuid.UUID
+	position    Vector2
+	hp          int
+	spawner     *Spawner
+	brain       npcBrain
+	nextAttack  time.Time
+}
+
+func newNPC(template *npcTemplate, room *Room, position Vector2) *NPC {
+	return &NPC{
+		id:          nextEntityID(),
+		template:    template,
+		currentRoom: room.ID,
+		position:    position,
+		hp:          template.MaxHP,
+		brain: npcBrain{
+			state: AI_STATE_IDLE,
+			home:  position,
+		},
+	}
+}
+
+func (n *NPC) ispassable() bool {
+	return false
+}
+
+func (n *NPC) getName() string {
+	return n.template.Name
+}
+
+func (n *NPC) getID() int64 {
+	return n.id
+}
+
+func (n *NPC) isPlayer() bool {
+	return false
+}
+
+func (n *NPC) isInteractable() bool {
+	return n.template.Interactable
+}
+
+func (n *NPC) getRoomID() uuid.UUID {
+	return n.currentRoom
+}
+
+func (n *NPC) getPosition() Vector2 {
+	return n.position
+}
+
+func (n *NPC) getCurrentHP() int {
+	return n.hp
+}
+
+func (n *NPC) getMaxHP() int {
+	return n.template.MaxHP
+}
+
+// loadNPCTemplates reads npc definitions from the data directory, creating a default file if there is none
+func loadNPCTemplates(dataPath string) (map[string]*npcTemplate, error) {
+	filePath := path.Join(dataPath, "npcs.json")
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		log.Info().Str("filepath", filePath).Msg("NPC data file does not exist, creating a new one")
+		defaults := []npcTemplate{
+			{
+				ID: "rat", Name: "Giant rat", Kind: NPC_KIND_MONSTER, MaxHP: 12, Experience: 15,
+				Behaviour: npcBehaviour{Wander: true, WanderRadius: 4, Aggressive: true, AggroRange: 3, LeashRange: 8, FleePercent: 25, MoveIntervalMs: 600},
+				Stats:     combatStats{Attack: 3, Defence: 1, Accuracy: 0, Evasion: 10, CritChance: 2, AttackIntervalMs: 1500},
+				Loot:      []lootEntry{{Item: "healing_potion", Chance: 30, Quantity: 1}},
+			},
+			{
+				ID: "goblin", Name: "Goblin", Kind: NPC_KIND_MONSTER, MaxHP: 30, Experience: 60,
+				Behaviour: npcBehaviour{Patrol: true, WanderRadius: 10, Aggressive: true, AggroRange: 5, LeashRange: 12, MoveIntervalMs: 400},
+				Stats:     combatStats{Attack: 6, Defence: 4, Accuracy: 5, Evasion: 5, CritChance: 5, AttackIntervalMs: 1200},
+				Loot:      []lootEntry{{Item: "rusty_sword", Chance: 20, Quantity: 1}, {Item: "iron_key", Chance: 10, Quantity: 1}},
+			},
+			{
+				ID: "merchant", Name: "Travelling merchant", Kind: NPC_KIND_NPC, MaxHP: 50, Interactable: true,
+				Behaviour: npcBehaviour{Wander: true, WanderRadius: 2, MoveIntervalMs: 2000},
+				Stats:     combatStats{Defence: 10, Evasion: 20},
+			},
+		}
+		jData, _ := json.MarshalIndent(defaults, "", " ")
+		if err := ioutil.WriteFile(filePath, jData, 0666); err != nil {
+			log.Warn().Err(err).Msg("Failed to write npc data file to disk")
+		}
+	}
+
+	fData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read npc data file from disk")
+		return nil, err
+	}
+
+	list := make([]*npcTemplate, 0)
+	if err := json.Unmarshal(fData, &list); err != nil {
+		log.Warn().Err(err).Msg("failed to unmarshal npc data")
+		return nil, err
+	}
+
+	templates := make(map[string]*npcTemplate, len(list))
+	for _, t := range list {
+		templates[t.ID] = t
+	}
+	log.Debug().Int("count", len(templates)).Msg("Loaded npc templates")
+	return templates, nil
+}
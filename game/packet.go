@@ -45,12 +45,16 @@ func (packet *Packet) GetMessageType() PacketType {
 // Get the packet as a byte array, ready for sending
 func (packet *Packet) GetBytes() []byte {
 	pSize := make([]byte, 4)
-	pType := make([]byte, 2)
-	binary.LittleEndian.PutUint16(pType, uint16(packet.Type))
 	binary.LittleEndian.PutUint32(pSize, uint32(len(packet.buffer)+2)) // +2 for packet type
 
-	pSize = append(pSize, pType...)
-	return append(pSize, packet.buffer...)
+	return append(pSize, packet.GetFrame()...)
+}
+
+// Get the packet type followed by the payload, transports add their own framing around it
+func (packet *Packet) GetFrame() []byte {
+	pType := make([]byte, 2, 2+len(packet.buffer))
+	binary.LittleEndian.PutUint16(pType, uint16(packet.Type))
+	return append(pType, packet.buffer...)
 }
 
 // Read UUID 16 byte long string
@@ -100,6 +104,14 @@ func (packet *Packet) ReadUInt32() (val uint32) {
 	return val
 }
 
+/**
+Read a 64 bit unsigned integer. This is 8 bytes
+*/
+func (packet *Packet) ReadUint64() uint64 {
+	val := binary.LittleEndian.Uint64(packet.ReadBytes(8))
+	return val
+}
+
 /**
 Read a 16 bit unsigned integer. This is 2 bytes
 */
@@ -127,6 +139,16 @@ func (packet *Packet) ReadUint8() uint8 {
 	return val
 }
 
+/**
+Read a position coordinate written by WriteCoordinate
+*/
+func (packet *Packet) ReadCoordinate(wide bool) int {
+	if wide {
+		return packet.ReadUint16AsInt()
+	}
+	return int(packet.ReadUint8())
+}
+
 /**
 Read a certain amount of bytes from the buffer.
 */
@@ -214,6 +236,17 @@ func (packet *Packet) WriteIntByte(data int) *Packet {
 	return packet
 }
 
+/**
+Write a position coordinate, one byte for clients that only know small rooms and a uint16 for wide ones
+*/
+func (packet *Packet) WriteCoordinate(data int, wide bool) *Packet {
+	if wide {
+		return packet.WriteUint16(uint16(data))
+	}
+	// Narrow clients are kept out of rooms larger than 255, see Room.fitsConnection
+	return packet.WriteUint8(uint8(data))
+}
+
 /**
 This is an alias method for WriteByte
 */
@@ -233,6 +266,28 @@ func (packet *Packet) WriteUint16(data uint16) *Packet {
 	return packet
 }
 
+/**
+Write a 4 byte, 32 bit unsigned integer to the buffer.
+*/
+func (packet *Packet) WriteUint32(data uint32) *Packet {
+	tBuffer := make([]byte, 4)
+
+	binary.LittleEndian.PutUint32(tBuffer, data)
+	packet.buffer = append(packet.buffer, tBuffer...)
+	return packet
+}
+
+/**
+Write an 8 byte, 64 bit unsigned integer to the buffer.
+*/
+func (packet *Packet) WriteUint64(data uint64) *Packet {
+	tBuffer := make([]byte, 8)
+
+	binary.LittleEndian.PutUint64(tBuffer, data)
+	packet.buffer = append(packet.buffer, tBuffer...)
+	return packet
+}
+
 /**
 Write a single byte as a boolean
 */
@@ -251,6 +306,19 @@ func (packet *Packet) WriteBool(data bool) *Packet {
 Parse the room into bits and write correct packets
 */
 func (packet *Packet) WriteRoomData(room *Room) {
+	packet.writeRoomData(room, nil)
+}
+
+/**
+Parse the room into bits, only writing the tiles the player has explored
+*/
+func (packet *Packet) WriteRoomDataForPlayer(room *Room, player *Player) {
+	packet.writeRoomData(room, func(x, y int) bool {
+		return player.hasExplored(room, x, y)
+	})
+}
+
+func (packet *Packet) writeRoomData(room *Room, include func(x, y int) bool) {
 	packet.WriteString(room.ID.String())
 	packet.WriteString(room.Name)
 	packet.WriteString(room.Description)
@@ -260,7 +328,9 @@ func (packet *Packet) WriteRoomData(room *Room) {
 	tiles := make([]Tile, 0)
 	for x, b := range room.Tiles {
 		for y := range b {
-			tiles = append(tiles, room.Tiles[x][y])
+			if include == nil || include(x, y) {
+				tiles = append(tiles, room.Tiles[x][y])
+			}
 		}
 	}
 
@@ -279,6 +349,35 @@ func (packet *Packet) WriteRoomData(room *Room) {
 	packet.WriteUint8(uint8(room.Entry.LocationInRoom.Y))
 }
 
+/**
+Write the public state of an entity
+*/
+func (packet *Packet) WriteEntityData(e entity) {
+	packet.writeEntityData(e, false)
+}
+
+/**
+Write the public state of an entity with coordinates as wide as the client understands
+*/
+func (packet *Packet) writeEntityData(e entity, wide bool) {
+	packet.WriteUint64(uint64(e.getID()))
+	packet.WriteBool(e.isPlayer())
+	packet.WriteString(e.getName())
+	switch v := e.(type) {
+	case *NPC:
+		packet.WriteString(v.template.ID)
+	case *groundItem:
+		packet.WriteString(v.template.ID)
+	default:
+		packet.WriteString("")
+	}
+	packet.WriteString(e.getRoomID().String())
+	packet.WriteCoordinate(e.getPosition().X, wide)
+	packet.WriteCoordinate(e.getPosition().Y, wide)
+	packet.WriteUint16(uint16(e.getCurrentHP()))
+	packet.WriteUint16(uint16(e.getMaxHP()))
+}
+
 func (packet *Packet) Reset(force bool) {
 	if force {
 		packet.buffer = make([]byte, 0)
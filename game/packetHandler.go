@@ -9,14 +9,31 @@ type PacketHandler interface {
 type RoomCountHandler struct{}
 
 /*
-We are asking to send us a list of rooms
+We are asking to send us a list of rooms. Clients before protocol version 3 don't hear about rooms they can't
+address, version 3 clients get the tiles in chunks after each room, only around the player once they joined.
 */
 func (r RoomCountHandler) handle(packet *Packet) {
+	connection := packet.Connection
 
 	for _, v := range ServerInstance.roomList {
+		if !v.fitsConnection(connection) {
+			continue
+		}
 		fmt.Println(fmt.Sprintf("Sending room %s upstream", v.ID))
 		msg := NewPacket(MsgRoomCountResponse)
-		msg.WriteRoomData(v)
-		sendMessageToConnection(packet.Connection, *msg)
+		connection.writeRoom(msg, v)
+		sendMessageToConnection(connection, *msg)
+
+		if !connection.wideCoordinates() {
+			continue
+		}
+		if player := connection.player; player != nil && !connection.isEditor {
+			if player.currentRoom == v.ID {
+				player.resetChunks(v)
+				player.streamChunks(v)
+			}
+			continue
+		}
+		connection.sendAllChunks(v)
 	}
 }
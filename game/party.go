@@ -0,0 +1,196 @@
+package game
+
+import "github.com/rs/zerolog/log"
+
+const (
+	maxPartySize = 6
+)
+
+// party is a group of players sharing a chat channel
+type party struct {
+	leader  *Player
+	members []*Player
+	invited map[int64]bool
+}
+
+func newParty(leader *Player) *party {
+	pt := &party{
+		leader:  leader,
+		members: []*Player{leader},
+		invited: make(map[int64]bool),
+	}
+	leader.party = pt
+	return pt
+}
+
+// invite asks another player to join, a party is created for the inviter if they aren't in one yet
+func (p *Player) invite(target *Player) uint8 {
+	if target == nil || target == p {
+		return CHAT_ERROR_NO_SUCH_PLAYER
+	}
+	if target.party != nil || target.isIgnoring(p) {
+		return CHAT_ERROR_NO_SUCH_PLAYER
+	}
+
+	pt := p.party
+	if pt == nil {
+		pt = newParty(p)
+	}
+	if len(pt.members) >= maxPartySize {
+		return CHAT_ERROR_PARTY_FULL
+	}
+	pt.invited[target.id] = true
+
+	pkt := NewPacket(MsgPartyInvite)
+	pkt.WriteUint64(uint64(p.id))
+	pkt.WriteString(p.name)
+	sendMessageToConnection(target.connection, *pkt)
+	return CHAT_OK
+}
+
+// join adds the player to the party of the inviter if they were invited. A full party changes nothing, the player
+// keeps their current party and the invite.
+func (p *Player) join(inviter *Player) uint8 {
+	if inviter == nil || inviter.party == nil || !inviter.party.invited[p.id] {
+		return CHAT_ERROR_NOT_IN_PARTY
+	}
+	pt := inviter.party
+	if len(pt.members) >= maxPartySize {
+		return CHAT_ERROR_PARTY_FULL
+	}
+	if p.party != nil {
+		p.leaveParty()
+	}
+
+	delete(pt.invited, p.id)
+	pt.members = append(pt.members, p)
+	p.party = pt
+	pt.sendUpdate()
+
+	log.Debug().Str("player", p.name).Str("leader", pt.leader.name).Msg("Player joined a party")
+	return CHAT_OK
+}
+
+// leaveParty removes the player from their party, the party is disbanded when one member is left
+func (p *Player) leaveParty() {
+	pt := p.party
+	if pt == nil {
+		return
+	}
+	p.party = nil
+
+	members := make([]*Player, 0, len(pt.members))
+	for _, m := range pt.members {
+		if m != p {
+			members = append(members, m)
+		}
+	}
+	pt.members = members
+
+	// Let the player know they are no longer in a party
+	if p.connection != nil {
+		empty := NewPacket(MsgPartyUpdate)
+		empty.WriteUint64(0)
+		empty.WriteUint8(0)
+		sendMessageToConnection(p.connection, *empty)
+	}
+
+	if len(pt.members) == 1 {
+		pt.members[0].leaveParty()
+		return
+	}
+	if len(pt.members) == 0 {
+		return
+	}
+	if pt.leader == p {
+		pt.leader = pt.members[0]
+	}
+	pt.sendUpdate()
+}
+
+/*
+sendUpdate tells every member who is in the party
+
+*****************************
+PARTY UPDATE STRUCTURE
+*****************************
+8 bytes - uint64 leader entity id, 0 when not in a party
+1 byte - number of members
+... 8 bytes - uint64 member entity id
+... 2 bytes + <n> bytes - member name
+*/
+func (pt *party) sendUpdate() {
+	pkt := NewPacket(MsgPartyUpdate)
+	pkt.WriteUint64(uint64(pt.leader.id))
+	pkt.WriteIntByte(len(pt.members))
+	for _, m := range pt.members {
+		pkt.WriteUint64(uint64(m.id))
+		pkt.WriteString(m.name)
+	}
+	for _, m := range pt.members {
+		if m.connection != nil {
+			sendMessageToConnection(m.connection, *pkt)
+		}
+	}
+}
+
+type PartyInviteHandler struct{}
+
+/*
+*****************************
+PARTY INVITE REQUEST STRUCTURE
+*****************************
+2 bytes + <n> bytes - name of the player to invite
+
+The invited player receives:
+8 bytes - uint64 inviter entity id
+2 bytes + <n> bytes - inviter name
+*/
+func (h PartyInviteHandler) handle(packet *Packet) {
+	name := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	player := packet.Connection.player
+	if player == nil {
+		sendChatError(packet.Connection, CHAT_ERROR_NOT_IN_WORLD)
+		return
+	}
+	if reason := player.invite(ServerInstance.findPlayerByName(name)); reason != CHAT_OK {
+		sendChatError(packet.Connection, reason)
+	}
+}
+
+type PartyAcceptHandler struct{}
+
+/*
+*****************************
+PARTY ACCEPT REQUEST STRUCTURE
+*****************************
+8 bytes - uint64 inviter entity id
+*/
+func (h PartyAcceptHandler) handle(packet *Packet) {
+	inviterID := int64(packet.ReadUint64())
+	player := packet.Connection.player
+	if player == nil {
+		sendChatError(packet.Connection, CHAT_ERROR_NOT_IN_WORLD)
+		return
+	}
+
+	var inviter *Player
+	for _, p := range ServerInstance.players() {
+		if p.id == inviterID {
+			inviter = p
+			break
+		}
+	}
+	if reason := player.join(inviter); reason != CHAT_OK {
+		sendChatError(packet.Connection, reason)
+	}
+}
+
+type PartyLeaveHandler struct{}
+
+// PartyLeaveHandler has no payload
+func (h PartyLeaveHandler) handle(packet *Packet) {
+	if player := packet.Connection.player; player != nil {
+		player.leaveParty()
+	}
+}
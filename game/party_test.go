@@ -0,0 +1,36 @@
+package game
+
+import "testing"
+
+func TestJoiningAFullPartyChangesNothing(t *testing.T) {
+	leader := &Player{id: 1, name: "leader"}
+	full := newParty(leader)
+	for i := int64(2); i <= maxPartySize; i++ {
+		full.members = append(full.members, &Player{id: i, name: "member"})
+	}
+
+	p := &Player{id: 100, name: "joiner"}
+	friend := &Player{id: 101, name: "friend"}
+	current := newParty(friend)
+	current.members = append(current.members, p)
+	p.party = current
+	full.invited[p.id] = true
+
+	if result := p.join(leader); result != CHAT_ERROR_PARTY_FULL {
+		t.Fatalf("Expected joining a full party to fail with %d but got %d", CHAT_ERROR_PARTY_FULL, result)
+	}
+	if p.party != current || len(current.members) != 2 {
+		t.Fatalf("Expected the player to stay in their party")
+	}
+	if !full.invited[p.id] {
+		t.Fatalf("Expected the player to keep the invite")
+	}
+
+	full.members = full.members[:maxPartySize-1]
+	if result := p.join(leader); result != CHAT_OK {
+		t.Fatalf("Expected joining once there is room to succeed but got %d", result)
+	}
+	if p.party != full || friend.party != nil {
+		t.Fatalf("Expected the player to move to the new party and the old one to disband")
+	}
+}
@@ -1,11 +1,57 @@
 package game
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
 type Player struct {
-	name        string
-	id          int64
-	currentRoom int64
-	hp          int
-	maxhp       int
+	name          string
+	id            int64
+	currentRoom   uuid.UUID
+	position      Vector2
+	hp            int
+	maxhp         int
+	connection    *Connection
+	path          []Vector2
+	route         []string
+	target        Vector2
+	nextMove      time.Time
+	explored      map[uuid.UUID][]bool
+	chunks        map[chunkKey]bool
+	stats         combatStats
+	nextAttack    time.Time
+	inventory     *Inventory
+	attributes    attributes
+	equipment     map[string]string
+	derived       derivedStats
+	level         int
+	experience    int
+	chat          chatState
+	ignored       []string
+	mutedChannels map[uint8]bool
+	party         *party
+	role          uint8
+}
+
+func NewPlayer(name string, connection *Connection) *Player {
+	p := &Player{
+		name:          name,
+		id:            nextEntityID(),
+		connection:    connection,
+		inventory:     NewInventory(),
+		attributes:    defaultAttributes(),
+		equipment:     make(map[string]string),
+		level:         1,
+		ignored:       make([]string, 0),
+		mutedChannels: make(map[uint8]bool),
+	}
+	p.derived = calculateStats(p.attributes, p.level, p.equipment)
+	p.stats = p.derived.combat
+	p.maxhp = p.derived.maxHP
+	p.hp = p.maxhp
+	return p
 }
 
 func (p *Player) ispassable() bool {
@@ -28,10 +74,14 @@ func (p *Player) isInteractable() bool {
 	return true
 }
 
-func (p *Player) getRoomID() int64 {
+func (p *Player) getRoomID() uuid.UUID {
 	return p.currentRoom
 }
 
+func (p *Player) getPosition() Vector2 {
+	return p.position
+}
+
 func (p *Player) getCurrentHP() int {
 	return p.hp
 }
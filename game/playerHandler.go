@@ -0,0 +1,215 @@
+package game
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type PlayerJoinHandler struct{}
+
+/*
+*****************************
+PLAYER JOIN REQUEST STRUCTURE
+*****************************
+2 bytes - uint16 player name length
+<n> bytes - player name
+2 bytes + <n> bytes - role token, optional, builders and admins can't join without theirs
+
+Response, preceded by TILE DEFINITIONS on success:
+1 byte - bool success
+2 bytes - uint16 message length
+<n> bytes - message
+... 8 bytes - uint64 entity id (only on success)
+... 2 bytes + 36 bytes - room id (only on success)
+... 1 byte - position X, uint16 from protocol version 3
+... 1 byte - position Y, uint16 from protocol version 3
+*/
+func (h PlayerJoinHandler) handle(packet *Packet) {
+	name := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	token := ""
+	if packet.UnreadLength() > 0 {
+		token = string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	}
+	connection := packet.Connection
+
+	response := NewPacket(MsgPlayerJoinResponse)
+	player, reason := joinWorld(connection, name, token)
+	if player == nil {
+		response.WriteBool(false).WriteString(reason)
+		sendMessageToConnection(connection, *response)
+		return
+	}
+
+	sendTileTypes(connection)
+	response.WriteBool(true).WriteString("Welcome")
+	response.WriteUint64(uint64(player.id))
+	response.WriteString(player.currentRoom.String())
+	response.WriteCoordinate(player.position.X, connection.wideCoordinates())
+	response.WriteCoordinate(player.position.Y, connection.wideCoordinates())
+	sendMessageToConnection(connection, *response)
+	player.sendInventory()
+	player.sendCharacterSheet()
+}
+
+// joinWorld loads the named player and places them in the starting room, on failure the reason is returned instead.
+// The token is checked for builders and admins, whether they are named in the config or were given the role in game.
+func joinWorld(connection *Connection, name, token string) (*Player, string) {
+	if connection.player != nil {
+		return nil, "Already joined"
+	}
+
+	if !validPlayerName.MatchString(name) {
+		return nil, "Invalid player name"
+	}
+	// Two connections playing the same character would overwrite each other's saves
+	if ServerInstance.findPlayerByName(name) != nil {
+		return nil, "That player is already online"
+	}
+
+	room := ServerInstance.findStartingRoom()
+	if room == nil {
+		return nil, "There are no rooms to join"
+	}
+	if !room.fitsConnection(connection) {
+		return nil, "The starting room is too large for your client, protocol version 3 is required"
+	}
+
+	player := NewPlayer(name, connection)
+	if err := player.loadAccount(); err != nil {
+		return nil, "Failed to load account"
+	}
+	// Builders and admins are only who they say they are with their token, anyone else could take their name
+	if ServerInstance.isAdmin(name) {
+		player.role = ROLE_ADMIN
+	}
+	if player.role > ROLE_PLAYER && !ServerInstance.validRoleToken(player.role, token) {
+		if player.role == ROLE_ADMIN {
+			return nil, "That name belongs to an admin, join with the admin token"
+		}
+		return nil, "That name belongs to a builder, join with the builder token"
+	}
+	connection.player = player
+	placePlayerInRoom(player, room, room.Entry.LocationInRoom)
+
+	log.Info().Str("player", name).Str("room", room.ID.String()).Msg("Player joined the world")
+	return player, ""
+}
+
+// placePlayerInRoom moves the player into the room and sends them everything that is already there
+func placePlayerInRoom(player *Player, room *Room, position Vector2) {
+	player.currentRoom = room.ID
+	player.position = position
+	room.addEntity(player)
+	player.streamChunks(room)
+	player.updateView(room)
+
+	for _, e := range room.entities {
+		if e.getID() == player.id {
+			continue
+		}
+		pkt := NewPacket(MsgEntitySpawn)
+		pkt.writeEntityData(e, player.connection.wideCoordinates())
+		sendMessageToConnection(player.connection, *pkt)
+	}
+}
+
+// removePlayerFromWorld saves the player and takes them out of whichever room they are in
+func removePlayerFromWorld(player *Player) {
+	player.saveAccount()
+	player.leaveParty()
+
+	if room := ServerInstance.FindRoom(player.currentRoom.String()); room != nil {
+		room.removeEntity(player, DESPAWN_REASON_LEFT)
+	}
+}
+
+type PlayerMoveHandler struct{}
+
+var moveDirections = []Vector2{{0, -1}, {1, 0}, {0, 1}, {-1, 0}, {1, -1}, {1, 1}, {-1, 1}, {-1, -1}}
+
+/*
+*****************************
+PLAYER MOVE REQUEST STRUCTURE
+*****************************
+1 byte - direction (0 - north, 1 - east, 2 - south, 3 - west, 4 - 7 diagonals clockwise starting north east)
+
+Moves sent faster than the move interval on the character sheet are dropped.
+*/
+func (h PlayerMoveHandler) handle(packet *Packet) {
+	direction := int(packet.ReadUint8())
+	player := packet.Connection.player
+	if player == nil || direction >= len(moveDirections) {
+		return
+	}
+	if direction >= 4 && !ServerInstance.config.DiagonalMovement {
+		return
+	}
+
+	stepPlayer(player, moveDirections[direction])
+}
+
+// stepPlayer moves the player a single tile, stepping on the exit takes them to the first destination.
+// Steps are limited to one per move interval of the character sheet, the same as walking along a path.
+func stepPlayer(player *Player, dir Vector2) bool {
+	room := ServerInstance.FindRoom(player.currentRoom.String())
+	if room == nil {
+		return false
+	}
+	now := time.Now()
+	if now.Before(player.nextMove) {
+		return false
+	}
+
+	player.clearPath()
+	pos := Vector2{player.position.X + dir.X, player.position.Y + dir.Y}
+	if !room.moveEntity(player, pos) {
+		return false
+	}
+	player.nextMove = now.Add(time.Millisecond * time.Duration(player.moveInterval()))
+
+	if pos == room.Exit.LocationInRoom {
+		for _, d := range room.Exit.Destinations {
+			if next := ServerInstance.FindRoom(d.String()); next != nil && next.fitsConnection(player.connection) {
+				transferPlayer(player, room, next)
+				break
+			}
+		}
+	}
+	return true
+}
+
+type PlayerMoveToHandler struct{}
+
+/*
+*******************************
+PLAYER MOVE TO REQUEST STRUCTURE
+*******************************
+2 bytes + 36 bytes - destination room id
+1 byte - position X, uint16 from protocol version 3
+1 byte - position Y, uint16 from protocol version 3
+
+Response:
+1 byte - bool success
+2 bytes - uint16 number of steps in the current room
+*/
+func (h PlayerMoveToHandler) handle(packet *Packet) {
+	roomID := packet.ReadUUID()
+	wide := packet.Connection.wideCoordinates()
+	target := Vector2{packet.ReadCoordinate(wide), packet.ReadCoordinate(wide)}
+
+	player := packet.Connection.player
+	if player == nil {
+		return
+	}
+
+	response := NewPacket(MsgPlayerMoveToResponse)
+	room := ServerInstance.FindRoom(roomID)
+	if room == nil || !room.IsPassable(target.X, target.Y) || !player.setDestination(room.ID, target) {
+		player.clearPath()
+		response.WriteBool(false).WriteUint16(0)
+	} else {
+		response.WriteBool(true).WriteUint16(uint16(len(player.path)))
+	}
+	sendMessageToConnection(packet.Connection, *response)
+}
@@ -0,0 +1,300 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxPrefabSize is the largest width or height a prefab saved from a room can have
+	maxPrefabSize = 64
+	// prefabPlacementAttempts is how many spots the room generator tries for every prefab before giving up
+	prefabPlacementAttempts = 10
+)
+
+// prefabAnchor is a named point of a prefab, stamping puts the anchor on the chosen position
+type prefabAnchor struct {
+	Name     string  `json:"name"`
+	Position Vector2 `json:"position"`
+}
+
+// prefab is a patch of tiles that can be stamped into any room. Tile positions are relative to the top left of the
+// patch, positions without a tile are left the way they are in the room.
+type prefab struct {
+	ID      string         `json:"id"`
+	Name    string         `json:"name"`
+	Width   int            `json:"width"`
+	Height  int            `json:"height"`
+	Tiles   []Tile         `json:"tiles"`
+	Anchors []prefabAnchor `json:"anchors"`
+	// Generate lets the room generator place the prefab in new rooms
+	Generate bool `json:"generate"`
+}
+
+// prefabFromRows builds a prefab from an ASCII drawing, # is a wall, . dirt, ~ water, O a portal and a space is left alone
+func prefabFromRows(id, name string, generate bool, rows []string, anchors ...prefabAnchor) *prefab {
+	types := map[rune]uint8{'#': TILE_TYPE_WALL, '.': TILE_TYPE_DIRT, '~': TILE_TYPE_WATER, 'O': TILE_TYPE_PORTAL}
+	p := &prefab{ID: id, Name: name, Height: len(rows), Anchors: anchors, Generate: generate}
+	for y, row := range rows {
+		for x, c := range row {
+			if x+1 > p.Width {
+				p.Width = x + 1
+			}
+			if t, found := types[c]; found {
+				p.Tiles = append(p.Tiles, Tile{Type: t, IsPassable: c != '#', Position: Vector2{x, y}})
+			}
+		}
+	}
+	return p
+}
+
+// transform mirrors a position of the prefab left to right and then turns it clockwise a number of quarter turns
+func (p *prefab) transform(pos Vector2, rotation int, mirror bool) Vector2 {
+	width, height := p.Width, p.Height
+	if mirror {
+		pos.X = width - 1 - pos.X
+	}
+	for i := 0; i < rotation%4; i++ {
+		pos = Vector2{height - 1 - pos.Y, pos.X}
+		width, height = height, width
+	}
+	return pos
+}
+
+// stamp returns the tiles of the prefab placed in a room so the anchor lands on the target position
+func (p *prefab) stamp(target Vector2, anchor, rotation int, mirror bool) []Tile {
+	origin := Vector2{}
+	if anchor < len(p.Anchors) {
+		origin = p.transform(p.Anchors[anchor].Position, rotation, mirror)
+	}
+
+	tiles := make([]Tile, 0, len(p.Tiles))
+	for _, tile := range p.Tiles {
+		pos := p.transform(tile.Position, rotation, mirror)
+		tile.Position = Vector2{target.X + pos.X - origin.X, target.Y + pos.Y - origin.Y}
+		tiles = append(tiles, tile)
+	}
+	return tiles
+}
+
+// stampPrefab stamps a prefab into the room as an edit that can be undone, the tiles go out to everyone viewing the room
+func (room *Room) stampPrefab(connection *Connection, id string, target Vector2, anchor, rotation int, mirror bool) (uint8, Vector2) {
+	p, found := ServerInstance.prefabs[id]
+	if !found || (anchor > 0 && anchor >= len(p.Anchors)) {
+		return ROOM_UPDATE_ERROR_UNKNOWN_PREFAB, target
+	}
+
+	tiles := p.stamp(target, anchor, rotation, mirror)
+	if result, pos := room.validateTiles(tiles); result != ROOM_UPDATE_OK {
+		return result, pos
+	}
+	now := time.Now()
+	for _, tile := range tiles {
+		if room.lockedFor(connection, tile.Position, now) != nil {
+			return ROOM_UPDATE_ERROR_LOCKED, tile.Position
+		}
+	}
+
+	room.editTiles(tiles, connection.editorName(), nil)
+	room.renewLocks(connection, now)
+	log.Info().Str("room", room.ID.String()).Str("prefab", id).Str("author", connection.editorName()).Msg("Prefab stamped")
+	return ROOM_UPDATE_OK, target
+}
+
+// placePrefabs lets the room generator drop a number of prefabs in a new room. A prefab is only placed where it keeps
+// off the outer walls and leaves the entry, the exit, every spawner and every waypoint walkable and reachable.
+func (room *Room) placePrefabs(count int) {
+	candidates := make([]*prefab, 0)
+	for _, id := range prefabIDs() {
+		if p := ServerInstance.prefabs[id]; p.Generate {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	for i := 0; i < count; i++ {
+		p := candidates[rand.Intn(len(candidates))]
+		for attempt := 0; attempt < prefabPlacementAttempts; attempt++ {
+			target := Vector2{rand.Intn(room.Width), rand.Intn(room.Height)}
+			tiles := p.stamp(target, 0, rand.Intn(4), rand.Intn(2) == 1)
+			if !room.fitsPrefab(tiles) {
+				continue
+			}
+			for _, tile := range tiles {
+				room.Tiles[tile.Position.X][tile.Position.Y] = tile
+			}
+			break
+		}
+	}
+}
+
+func (room *Room) fitsPrefab(tiles []Tile) bool {
+	overlay := tileOverlay{room: room, tiles: make(map[Vector2]Tile, len(tiles))}
+	for _, tile := range tiles {
+		pos := tile.Position
+		if pos.X < 1 || pos.Y < 1 || pos.X > room.Width-2 || pos.Y > room.Height-2 {
+			return false
+		}
+		overlay.tiles[pos] = tile
+	}
+	if result, _ := room.validateTiles(tiles); result != ROOM_UPDATE_OK {
+		return false
+	}
+
+	keep := []Vector2{room.Exit.LocationInRoom}
+	for _, spawner := range room.Spawners {
+		keep = append(keep, spawner.Position)
+		keep = append(keep, spawner.Waypoints...)
+	}
+	for _, pos := range keep {
+		if !room.reachable(overlay, room.Entry.LocationInRoom, pos) {
+			return false
+		}
+	}
+	return true
+}
+
+func prefabIDs() []string {
+	ids := make([]string, 0, len(ServerInstance.prefabs))
+	for id := range ServerInstance.prefabs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// savePrefab captures a region of the room as a prefab, a prefab with the same id is replaced
+func (room *Room) savePrefab(id, name string, region roomRegion, anchors []prefabAnchor) uint8 {
+	if !validPlayerName.MatchString(id) || !validRoomName(name) {
+		return ROOM_UPDATE_ERROR_INVALID_NAME
+	}
+	if region.whole() || region.Width > maxPrefabSize || region.Height > maxPrefabSize {
+		return ROOM_UPDATE_ERROR_INVALID_SIZE
+	}
+	if region.X < 0 || region.Y < 0 || region.X+region.Width > room.Width || region.Y+region.Height > room.Height {
+		return ROOM_UPDATE_ERROR_OUT_OF_BOUNDS
+	}
+	for _, anchor := range anchors {
+		if anchor.Position.X >= region.Width || anchor.Position.Y >= region.Height {
+			return ROOM_UPDATE_ERROR_OUT_OF_BOUNDS
+		}
+	}
+
+	p := &prefab{ID: id, Name: name, Width: region.Width, Height: region.Height, Anchors: anchors}
+	for x := 0; x < region.Width; x++ {
+		for y := 0; y < region.Height; y++ {
+			tile := room.Tiles[region.X+x][region.Y+y]
+			tile.Position = Vector2{x, y}
+			p.Tiles = append(p.Tiles, tile)
+		}
+	}
+	ServerInstance.prefabs[id] = p
+	savePrefabs()
+	log.Info().Str("prefab", id).Str("room", room.ID.String()).Msg("Prefab saved")
+	return ROOM_UPDATE_OK
+}
+
+func prefabsPath(dataPath string) string {
+	return path.Join(dataPath, "prefabs.json")
+}
+
+// savePrefabs writes the whole prefab library back to the data directory
+func savePrefabs() {
+	list := make([]*prefab, 0, len(ServerInstance.prefabs))
+	for _, id := range prefabIDs() {
+		list = append(list, ServerInstance.prefabs[id])
+	}
+	jData, _ := json.MarshalIndent(list, "", " ")
+	if err := ioutil.WriteFile(prefabsPath(ServerInstance.dataPath), jData, 0666); err != nil {
+		log.Warn().Err(err).Msg("Failed to write prefab data file to disk")
+	}
+}
+
+// loadPrefabs reads the prefab library from the data directory, creating a default file if there is none
+func loadPrefabs(dataPath string) (map[string]*prefab, error) {
+	filePath := prefabsPath(dataPath)
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		log.Info().Str("filepath", filePath).Msg("Prefab data file does not exist, creating a new one")
+		defaults := []*prefab{
+			prefabFromRows("pillar", "Pillar", true, []string{
+				"...",
+				".#.",
+				"...",
+			}, prefabAnchor{Name: "centre", Position: Vector2{1, 1}}),
+			prefabFromRows("pond", "Pond", true, []string{
+				" ... ",
+				".~~~.",
+				".~~~.",
+				" ... ",
+			}, prefabAnchor{Name: "centre", Position: Vector2{2, 1}}),
+			prefabFromRows("corridor", "Corridor", false, []string{
+				"#####",
+				".....",
+				"#####",
+			}, prefabAnchor{Name: "west", Position: Vector2{0, 1}}, prefabAnchor{Name: "east", Position: Vector2{4, 1}}),
+			prefabFromRows("shop", "Shop", false, []string{
+				"#######",
+				"#.....#",
+				"#.###.#",
+				"#.....#",
+				"###.###",
+			}, prefabAnchor{Name: "door", Position: Vector2{3, 4}}),
+			prefabFromRows("arena", "Boss arena", false, []string{
+				"###########",
+				"#.........#",
+				"#.#.....#.#",
+				"#.........#",
+				"#.........#",
+				"#.#.....#.#",
+				"#.........#",
+				"#####.#####",
+			}, prefabAnchor{Name: "gate", Position: Vector2{5, 7}}, prefabAnchor{Name: "centre", Position: Vector2{5, 4}}),
+		}
+		jData, _ := json.MarshalIndent(defaults, "", " ")
+		if err := ioutil.WriteFile(filePath, jData, 0666); err != nil {
+			log.Warn().Err(err).Msg("Failed to write prefab data file to disk")
+		}
+	}
+
+	fData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read prefab data file from disk")
+		return nil, err
+	}
+
+	list := make([]*prefab, 0)
+	if err := json.Unmarshal(fData, &list); err != nil {
+		log.Warn().Err(err).Msg("failed to unmarshal prefab data")
+		return nil, err
+	}
+
+	prefabs := make(map[string]*prefab, len(list))
+	for _, p := range list {
+		if p == nil {
+			log.Warn().Msg("Skipping an empty prefab in the prefab data file")
+			continue
+		}
+		// Hand edited files may leave the size out
+		for _, tile := range p.Tiles {
+			if tile.Position.X >= p.Width {
+				p.Width = tile.Position.X + 1
+			}
+			if tile.Position.Y >= p.Height {
+				p.Height = tile.Position.Y + 1
+			}
+		}
+		prefabs[p.ID] = p
+	}
+	log.Debug().Int("count", len(prefabs)).Msg("Loaded prefabs")
+	return prefabs, nil
+}
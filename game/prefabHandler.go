@@ -0,0 +1,150 @@
+package game
+
+import "strings"
+
+type PrefabStampHandler struct{}
+
+/*
+*****************************
+PREFAB STAMP REQUEST STRUCTURE
+*****************************
+2 bytes + <n> bytes - prefab id
+2 bytes + 36 bytes - room id
+1 byte - position X, uint16 from protocol version 3
+1 byte - position Y, uint16 from protocol version 3
+1 byte - anchor index, the anchor is placed on the position
+1 byte - clockwise quarter turns (0 - 3)
+1 byte - bool mirror left to right, done before turning
+
+Only builders can stamp prefabs. The stamp is validated like any other edit and can be undone, a rejected stamp is
+answered with a room update result followed by ROOM EDIT REJECTED. The tiles go out as a delta to everyone viewing
+the room, including the builder.
+*/
+func (h PrefabStampHandler) handle(packet *Packet) {
+	connection := packet.Connection
+	id := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	roomID := packet.ReadUUID()
+	wide := connection.wideCoordinates()
+	target := Vector2{packet.ReadCoordinate(wide), packet.ReadCoordinate(wide)}
+	anchor := int(packet.ReadUint8())
+	rotation := int(packet.ReadUint8())
+	mirror := packet.ReadBoolean()
+
+	room := ServerInstance.FindRoom(roomID)
+	if connection.player == nil || connection.player.role < ROLE_BUILDER {
+		sendRoomUpdateResult(connection, MsgPrefabStampRequest, ROOM_FIELD_NONE, roomID, ROOM_UPDATE_ERROR_NOT_ALLOWED)
+		return
+	}
+	if room == nil {
+		sendRoomUpdateResult(connection, MsgPrefabStampRequest, ROOM_FIELD_NONE, roomID, ROOM_UPDATE_ERROR_UNKNOWN_ROOM)
+		return
+	}
+
+	connection.watch(room)
+	result, pos := room.stampPrefab(connection, id, target, anchor, rotation, mirror)
+	sendRoomUpdateResult(connection, MsgPrefabStampRequest, ROOM_FIELD_NONE, roomID, result)
+	if result != ROOM_UPDATE_OK {
+		sendEditRejected(connection, room, result, pos)
+	}
+}
+
+type PrefabSaveHandler struct{}
+
+/*
+*****************************
+PREFAB SAVE REQUEST STRUCTURE
+*****************************
+2 bytes + <n> bytes - prefab id, letters, digits, - and _ only, an existing prefab with the id is replaced
+2 bytes + <n> bytes - prefab name
+2 bytes + 36 bytes - room id to copy the tiles from
+1 byte - region X, uint16 from protocol version 3
+1 byte - region Y, uint16 from protocol version 3
+1 byte - region width, uint16 from protocol version 3, at most 64
+1 byte - region height, uint16 from protocol version 3, at most 64
+1 byte - number of anchors
+... 2 bytes + <n> bytes - anchor name
+... 1 byte - anchor X inside of the region, uint16 from protocol version 3
+... 1 byte - anchor Y inside of the region, uint16 from protocol version 3
+
+Only builders can save prefabs. The answer is a room update result.
+*/
+func (h PrefabSaveHandler) handle(packet *Packet) {
+	connection := packet.Connection
+	id := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	name := strings.TrimSpace(string(packet.ReadBytes(uint32(packet.ReadUint16()))))
+	roomID := packet.ReadUUID()
+	wide := connection.wideCoordinates()
+	region := roomRegion{
+		X:      packet.ReadCoordinate(wide),
+		Y:      packet.ReadCoordinate(wide),
+		Width:  packet.ReadCoordinate(wide),
+		Height: packet.ReadCoordinate(wide),
+	}
+	count := int(packet.ReadUint8())
+	anchors := make([]prefabAnchor, 0, count)
+	for i := 0; i < count; i++ {
+		anchorName := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+		anchors = append(anchors, prefabAnchor{
+			Name:     anchorName,
+			Position: Vector2{packet.ReadCoordinate(wide), packet.ReadCoordinate(wide)},
+		})
+	}
+
+	result := ROOM_UPDATE_ERROR_UNKNOWN_ROOM
+	if connection.player == nil || connection.player.role < ROLE_BUILDER {
+		result = ROOM_UPDATE_ERROR_NOT_ALLOWED
+	} else if room := ServerInstance.FindRoom(roomID); room != nil {
+		result = room.savePrefab(id, name, region, anchors)
+	}
+	sendRoomUpdateResult(connection, MsgPrefabSaveRequest, ROOM_FIELD_NONE, roomID, result)
+}
+
+type PrefabListHandler struct{}
+
+/*
+*****************************
+PREFAB LIST REQUEST STRUCTURE
+*****************************
+Empty
+
+Response:
+2 bytes - uint16 number of prefabs
+... 2 bytes + <n> bytes - prefab id
+... 2 bytes + <n> bytes - prefab name
+... 2 bytes - uint16 width
+... 2 bytes - uint16 height
+... 1 byte - number of anchors
+... ... 2 bytes + <n> bytes - anchor name
+... ... 2 bytes - uint16 anchor X
+... ... 2 bytes - uint16 anchor Y
+... 2 bytes - uint16 number of tiles, positions without a tile are left alone when stamping
+... ... 1 byte - tile type uint8 (max 255)
+... ... 1 byte - is passable byte
+... ... 2 bytes - uint16 position X
+... ... 2 bytes - uint16 position Y
+*/
+func (h PrefabListHandler) handle(packet *Packet) {
+	pkt := NewPacket(MsgPrefabList)
+	pkt.WriteUint16(uint16(len(ServerInstance.prefabs)))
+	for _, id := range prefabIDs() {
+		p := ServerInstance.prefabs[id]
+		pkt.WriteString(p.ID).
+			WriteString(p.Name).
+			WriteUint16(uint16(p.Width)).
+			WriteUint16(uint16(p.Height)).
+			WriteUint8(uint8(len(p.Anchors)))
+		for _, anchor := range p.Anchors {
+			pkt.WriteString(anchor.Name).
+				WriteUint16(uint16(anchor.Position.X)).
+				WriteUint16(uint16(anchor.Position.Y))
+		}
+		pkt.WriteUint16(uint16(len(p.Tiles)))
+		for _, tile := range p.Tiles {
+			pkt.WriteUint8(tile.Type).
+				WriteBool(tile.IsPassable).
+				WriteUint16(uint16(tile.Position.X)).
+				WriteUint16(uint16(tile.Position.Y))
+		}
+	}
+	sendMessageToConnection(packet.Connection, *pkt)
+}
@@ -0,0 +1,22 @@
+package game
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestLoadPrefabsSkipsEmptyEntries(t *testing.T) {
+	dataPath := t.TempDir()
+	prefabs := `[{"id": "pillar", "name": "Pillar", "tiles": [{"type": 0}]}, null]`
+	if err := ioutil.WriteFile(prefabsPath(dataPath), []byte(prefabs), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadPrefabs(dataPath)
+	if err != nil {
+		t.Fatalf("Expected the prefab data to load but got %v", err)
+	}
+	if len(loaded) != 1 || loaded["pillar"] == nil {
+		t.Fatalf("Expected only the pillar prefab to be loaded but got %d prefabs", len(loaded))
+	}
+}
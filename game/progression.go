@@ -0,0 +1,195 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	XP_SOURCE_KILL = uint8(iota)
+	XP_SOURCE_QUEST
+)
+
+// levelDefinition is a single row of the progression table
+type levelDefinition struct {
+	Level      int        `json:"level"`
+	Experience int        `json:"experience"`
+	Attributes attributes `json:"attributes"`
+	MaxHP      int        `json:"max_hp"`
+}
+
+// progressionTable holds the level curve, the experience values are the total needed to reach each level
+type progressionTable struct {
+	Levels []levelDefinition `json:"levels"`
+}
+
+// maxLevel returns the highest level that can be reached
+func (t *progressionTable) maxLevel() int {
+	if len(t.Levels) == 0 {
+		return 1
+	}
+	return t.Levels[len(t.Levels)-1].Level
+}
+
+// experienceFor returns the total experience required to reach the level, -1 if it can't be reached
+func (t *progressionTable) experienceFor(level int) int {
+	for _, l := range t.Levels {
+		if l.Level == level {
+			return l.Experience
+		}
+	}
+	return -1
+}
+
+// levelFor returns the level that belongs to the amount of experience
+func (t *progressionTable) levelFor(experience int) int {
+	level := 1
+	for _, l := range t.Levels {
+		if experience >= l.Experience && l.Level > level {
+			level = l.Level
+		}
+	}
+	return level
+}
+
+// bonuses sums up the attribute and hp growth of every level up to and including the given one
+func (t *progressionTable) bonuses(level int) (attributes, int) {
+	total := attributes{}
+	hp := 0
+	for _, l := range t.Levels {
+		if l.Level <= level {
+			total = total.add(l.Attributes)
+			hp += l.MaxHP
+		}
+	}
+	return total, hp
+}
+
+func defaultProgressionTable() progressionTable {
+	table := progressionTable{Levels: []levelDefinition{{Level: 1}}}
+	experience := 0
+	for level := 2; level <= 20; level++ {
+		experience += 50 * level * level
+		growth := attributes{Strength: 1, Vitality: 1}
+		if level%2 == 0 {
+			growth.Agility = 1
+		} else {
+			growth.Perception = 1
+		}
+		table.Levels = append(table.Levels, levelDefinition{
+			Level:      level,
+			Experience: experience,
+			Attributes: growth,
+			MaxHP:      10,
+		})
+	}
+	return table
+}
+
+// loadProgressionTable reads the level curve from the config directory, creating a default file if there is none
+func loadProgressionTable(configPath string) (*progressionTable, error) {
+	filePath := path.Join(configPath, "progression.json")
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		log.Info().Str("filepath", filePath).Msg("Progression file does not exist, creating a new one")
+		jData, _ := json.MarshalIndent(defaultProgressionTable(), "", " ")
+		if err := ioutil.WriteFile(filePath, jData, 0666); err != nil {
+			log.Warn().Err(err).Msg("Failed to write progression file to disk")
+		}
+	}
+
+	fData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read progression file from disk")
+		return nil, err
+	}
+
+	table := &progressionTable{}
+	if err := json.Unmarshal(fData, table); err != nil {
+		log.Warn().Err(err).Msg("failed to unmarshal progression data")
+		return nil, err
+	}
+	log.Debug().Int("max_level", table.maxLevel()).Msg("Loaded progression table")
+	return table, nil
+}
+
+/*
+awardExperience gives the player experience and levels them up when they pass a threshold
+
+*****************************
+EXPERIENCE GAIN STRUCTURE
+*****************************
+4 bytes - uint32 experience gained
+1 byte - source (0 - kill, 1 - quest)
+4 bytes - uint32 total experience
+4 bytes - uint32 experience needed for the next level, 0 at max level
+
+*****************************
+LEVEL UP STRUCTURE
+*****************************
+8 bytes - uint64 player entity id
+2 bytes - uint16 new level
+... 2 bytes - uint16 strength, agility, vitality, perception gained
+2 bytes - uint16 max hp gained
+*/
+func (p *Player) awardExperience(amount int, source uint8) {
+	if amount <= 0 {
+		return
+	}
+
+	table := ServerInstance.progression
+	p.experience += amount
+
+	newLevel := table.levelFor(p.experience)
+	if newLevel > p.level {
+		oldAttributes, oldHP := table.bonuses(p.level)
+		newAttributes, newHP := table.bonuses(newLevel)
+		p.level = newLevel
+
+		pkt := NewPacket(MsgLevelUp)
+		pkt.WriteUint64(uint64(p.id))
+		pkt.WriteUint16(uint16(newLevel))
+		pkt.WriteUint16(uint16(newAttributes.Strength - oldAttributes.Strength))
+		pkt.WriteUint16(uint16(newAttributes.Agility - oldAttributes.Agility))
+		pkt.WriteUint16(uint16(newAttributes.Vitality - oldAttributes.Vitality))
+		pkt.WriteUint16(uint16(newAttributes.Perception - oldAttributes.Perception))
+		pkt.WriteUint16(uint16(newHP - oldHP))
+
+		// Everyone in the room gets to see the level up
+		if room := ServerInstance.FindRoom(p.currentRoom.String()); room != nil {
+			room.broadcast(pkt)
+		} else if p.connection != nil {
+			sendMessageToConnection(p.connection, *pkt)
+		}
+
+		p.recalculateStats()
+		// A level up fully heals the player
+		p.hp = p.maxhp
+		log.Info().Str("player", p.name).Int("player_level", newLevel).Msg("Player levelled up")
+	}
+
+	if p.connection != nil {
+		pkt := NewPacket(MsgExperienceGain)
+		pkt.WriteUint32(uint32(amount))
+		pkt.WriteUint8(source)
+		pkt.WriteUint32(uint32(p.experience))
+		pkt.WriteUint32(uint32(p.nextLevelExperience()))
+		sendMessageToConnection(p.connection, *pkt)
+	}
+
+	p.sendCharacterSheet()
+	p.saveAccount()
+}
+
+// nextLevelExperience returns the total experience needed for the next level, 0 at max level
+func (p *Player) nextLevelExperience() int {
+	next := ServerInstance.progression.experienceFor(p.level + 1)
+	if next < 0 {
+		return 0
+	}
+	return next
+}
@@ -3,7 +3,9 @@ package game
 import (
 	"encoding/binary"
 	"fmt"
+	"github.com/Entrio/aeonofstrife/pathfinding"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -11,6 +13,7 @@ const (
 	TILE_TYPE_DIRT
 	TILE_TYPE_PORTAL
 	TILE_TYPE_AIR
+	TILE_TYPE_WATER
 )
 
 type Vector2 struct {
@@ -34,7 +37,15 @@ type Room struct {
 	Entry          RoomEntryPoint `json:"entry"`
 	Exit           RoomExitPoint  `json:"exit"`
 	IsStartingRoom bool           `json:"is_starting_room"`
+	Spawners       []*Spawner     `json:"spawners"`
 	isActive       bool           `json:"is_active"`
+	entities       map[int64]entity
+	revision       uint64
+	history        []roomEdit
+	undo           []roomEdit
+	redo           []roomEdit
+	locks          []*roomLock
+	pathCache      *pathfinding.Cache
 }
 
 type RoomEntryPoint struct {
@@ -72,17 +83,25 @@ func NewRoom(width, height int) *Room {
 			Destinations:   nil,
 		},
 		IsStartingRoom: false,
+		Spawners:       make([]*Spawner, 0),
+		entities:       make(map[int64]entity),
+		pathCache:      pathfinding.NewCache(),
 	}
 }
 
-func (room *Room) UpdateTile(x, y uint8, tile Tile) *Room {
+func (room *Room) UpdateTile(x, y int, tile Tile) *Room {
 	fmt.Println(
 		fmt.Sprintf(
 			"Updating tile for room %s: %d",
 			room.ID, tile.Type,
 		),
 	)
-	room.Tiles[x][y] = tile
+	tile.Position = Vector2{x, y}
+	if result, _ := room.validateTiles([]Tile{tile}); result != ROOM_UPDATE_OK {
+		log.Warn().Str("room", room.ID.String()).Int("x", x).Int("y", y).Uint8("result", result).Msg("Rejected tile update")
+		return room
+	}
+	room.applyTiles([]Tile{tile}, "")
 	return room
 }
 
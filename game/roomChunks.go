@@ -0,0 +1,214 @@
+package game
+
+import "github.com/google/uuid"
+
+// ROOM_CHUNK_SIZE is the width and height of the square pieces v3 clients receive rooms in
+const ROOM_CHUNK_SIZE = 32
+
+const (
+	// maxNarrowRoomSize is the largest room width or height clients before protocol version 3 can address
+	maxNarrowRoomSize = 255
+	maxRoomSize       = 65535
+	// minRoomSize leaves a single floor tile inside the walls
+	minRoomSize = 3
+)
+
+type chunkKey struct {
+	room uuid.UUID
+	x, y int
+}
+
+// wideCoordinates reports whether the connection gets positions as uint16 instead of a single byte
+func (connection *Connection) wideCoordinates() bool {
+	return connection != nil && connection.protocolVersion >= PROTOCOL_VERSION_3
+}
+
+// isLarge reports whether the room has positions that don't fit in a single byte
+func (room *Room) isLarge() bool {
+	return room.Width > maxNarrowRoomSize || room.Height > maxNarrowRoomSize
+}
+
+// fitsConnection reports whether the client on the other end can address every tile of the room
+func (room *Room) fitsConnection(connection *Connection) bool {
+	return !room.isLarge() || connection == nil || connection.wideCoordinates()
+}
+
+// chunkCount returns how many chunks the room is split into along each axis
+func (room *Room) chunkCount() (int, int) {
+	return (room.Width + ROOM_CHUNK_SIZE - 1) / ROOM_CHUNK_SIZE, (room.Height + ROOM_CHUNK_SIZE - 1) / ROOM_CHUNK_SIZE
+}
+
+// broadcastPositions sends every player in the room the packet built with coordinates as wide as their client understands
+func (room *Room) broadcastPositions(build func(wide bool) *Packet) {
+	var narrow, wide *Packet
+	for _, p := range room.players() {
+		if p.connection == nil {
+			continue
+		}
+		if p.connection.wideCoordinates() {
+			if wide == nil {
+				wide = build(true)
+			}
+			sendMessageToConnection(p.connection, *wide)
+			continue
+		}
+		if narrow == nil {
+			narrow = build(false)
+		}
+		sendMessageToConnection(p.connection, *narrow)
+	}
+}
+
+/*
+*****************************
+ROOM DATA V3 STRUCTURE
+*****************************
+2 bytes + <n> bytes - room id
+2 bytes + <n> bytes - room name
+2 bytes + <n> bytes - room description
+2 bytes - room width uint16
+2 bytes - room height uint16
+1 byte - chunk size, tiles follow in MsgRoomChunk packets
+2 bytes - room exit position X
+2 bytes - room exit position Y
+2 bytes - room entry position X
+2 bytes - room entry position Y
+*/
+func (packet *Packet) writeRoomHeaderV3(room *Room) {
+	packet.WriteString(room.ID.String())
+	packet.WriteString(room.Name)
+	packet.WriteString(room.Description)
+	packet.WriteUint16(uint16(room.Width))
+	packet.WriteUint16(uint16(room.Height))
+	packet.WriteUint8(ROOM_CHUNK_SIZE)
+	packet.WriteUint16(uint16(room.Exit.LocationInRoom.X))
+	packet.WriteUint16(uint16(room.Exit.LocationInRoom.Y))
+	packet.WriteUint16(uint16(room.Entry.LocationInRoom.X))
+	packet.WriteUint16(uint16(room.Entry.LocationInRoom.Y))
+}
+
+/**
+Write a single chunk of the room, cx and cy are chunk coordinates
+*/
+func (packet *Packet) WriteRoomChunk(room *Room, cx, cy int) {
+	packet.writeRoomChunk(room, cx, cy, nil)
+}
+
+/*
+*****************************
+ROOM CHUNK STRUCTURE
+*****************************
+2 bytes + <n> bytes - room id
+2 bytes - chunk X uint16, the first tile is at X * chunk size
+2 bytes - chunk Y uint16
+1 byte - chunk width, chunks on the right edge of the room can be narrower
+1 byte - chunk height, chunks on the bottom edge of the room can be shorter
+... tiles of the chunk, laid out like the tiles of ROOM DATA V2
+*/
+func (packet *Packet) writeRoomChunk(room *Room, cx, cy int, include func(x, y int) bool) {
+	x0, y0 := cx*ROOM_CHUNK_SIZE, cy*ROOM_CHUNK_SIZE
+	width, height := minInt(ROOM_CHUNK_SIZE, room.Width-x0), minInt(ROOM_CHUNK_SIZE, room.Height-y0)
+
+	packet.WriteString(room.ID.String())
+	packet.WriteUint16(uint16(cx))
+	packet.WriteUint16(uint16(cy))
+	packet.WriteIntByte(width)
+	packet.WriteIntByte(height)
+	packet.writeTileRuns(room, x0, y0, width, height, include)
+}
+
+// sendAllChunks sends every chunk of the room, editors and clients that haven't joined yet get the whole map
+func (connection *Connection) sendAllChunks(room *Room) {
+	columns, rows := room.chunkCount()
+	for cy := 0; cy < rows; cy++ {
+		for cx := 0; cx < columns; cx++ {
+			pkt := NewPacket(MsgRoomChunk)
+			pkt.writeRoomChunk(room, cx, cy, connection.includeTile(room))
+			sendMessageToConnection(connection, *pkt)
+		}
+	}
+}
+
+// chunkRadius is how many chunks around the one the player stands in are kept loaded
+func chunkRadius() int {
+	return 1 + viewRadius()/ROOM_CHUNK_SIZE
+}
+
+/*
+streamChunks sends the chunks around the player that their client doesn't have yet and tells it to drop
+the ones that are now far away. Chunks are only unloaded one chunk past the load radius so walking back
+and forth over a chunk border doesn't resend anything. Clients drop every chunk of a room when they leave it.
+
+*****************************
+ROOM CHUNK UNLOAD STRUCTURE
+*****************************
+2 bytes + <n> bytes - room id
+2 bytes - uint16 number of chunks
+... 2 bytes - chunk X uint16
+... 2 bytes - chunk Y uint16
+*/
+func (p *Player) streamChunks(room *Room) {
+	if p.connection == nil || !p.connection.wideCoordinates() || p.connection.telnet != nil {
+		return
+	}
+
+	if p.chunks == nil {
+		p.chunks = make(map[chunkKey]bool)
+	}
+	for key := range p.chunks {
+		if key.room != room.ID {
+			delete(p.chunks, key)
+		}
+	}
+
+	radius := chunkRadius()
+	current := Vector2{p.position.X / ROOM_CHUNK_SIZE, p.position.Y / ROOM_CHUNK_SIZE}
+	columns, rows := room.chunkCount()
+
+	unload := make([]chunkKey, 0)
+	for key := range p.chunks {
+		if distance(Vector2{key.x, key.y}, current) > radius+1 {
+			unload = append(unload, key)
+			delete(p.chunks, key)
+		}
+	}
+	if len(unload) > 0 {
+		pkt := NewPacket(MsgRoomChunkUnload)
+		pkt.WriteString(room.ID.String())
+		pkt.WriteUint16(uint16(len(unload)))
+		for _, key := range unload {
+			pkt.WriteUint16(uint16(key.x)).WriteUint16(uint16(key.y))
+		}
+		sendMessageToConnection(p.connection, *pkt)
+	}
+
+	include := p.connection.includeTile(room)
+	for cy := clamp(current.Y-radius, 0, rows-1); cy <= clamp(current.Y+radius, 0, rows-1); cy++ {
+		for cx := clamp(current.X-radius, 0, columns-1); cx <= clamp(current.X+radius, 0, columns-1); cx++ {
+			key := chunkKey{room: room.ID, x: cx, y: cy}
+			if p.chunks[key] {
+				continue
+			}
+			p.chunks[key] = true
+			pkt := NewPacket(MsgRoomChunk)
+			pkt.writeRoomChunk(room, cx, cy, include)
+			sendMessageToConnection(p.connection, *pkt)
+		}
+	}
+}
+
+// resetChunks forgets which chunks of the room the client has so the next stream sends them again
+func (p *Player) resetChunks(room *Room) {
+	for key := range p.chunks {
+		if key.room == room.ID {
+			delete(p.chunks, key)
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,252 @@
+package game
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type RoomCreateHandler struct{}
+
+/*
+*****************************
+ROOM CREATE REQUEST STRUCTURE
+*****************************
+2 bytes + <n> bytes - room name, an empty name keeps the generated one
+2 bytes - uint16 room width, between the configured min_width and max_width
+2 bytes - uint16 room height, between the configured min_height and max_height
+
+The result carries the id of the new room, which is sent right after it like a room list entry.
+*/
+func (h RoomCreateHandler) handle(packet *Packet) {
+	name := strings.TrimSpace(string(packet.ReadBytes(uint32(packet.ReadUint16()))))
+	width := packet.ReadUint16AsInt()
+	height := packet.ReadUint16AsInt()
+
+	connection := packet.Connection
+	if connection.player == nil || connection.player.role < ROLE_BUILDER {
+		sendCreatedRoom(connection, MsgRoomCreateRequest, nil, ROOM_UPDATE_ERROR_NOT_ALLOWED)
+		return
+	}
+	room, result := createRoom(name, width, height)
+	sendCreatedRoom(connection, MsgRoomCreateRequest, room, result)
+}
+
+func createRoom(name string, width, height int) (*Room, uint8) {
+	minWidth, maxWidth := roomWidthBounds()
+	minHeight, maxHeight := roomHeightBounds()
+	if width < minWidth || width > maxWidth || height < minHeight || height > maxHeight {
+		return nil, ROOM_UPDATE_ERROR_INVALID_SIZE
+	}
+	if name != "" && !validRoomName(name) {
+		return nil, ROOM_UPDATE_ERROR_INVALID_NAME
+	}
+
+	room := generateRoom(width, height)
+	room.placePrefabs(ServerInstance.config.RoomData.Prefabs)
+	if name != "" {
+		room.Name = name
+	}
+	ServerInstance.roomList[room.ID.String()] = room
+	room.save()
+
+	log.Info().Str("room", room.ID.String()).Int("width", width).Int("height", height).Msg("Room created")
+	return room, ROOM_UPDATE_OK
+}
+
+type RoomCloneHandler struct{}
+
+/*
+*****************************
+ROOM CLONE REQUEST STRUCTURE
+*****************************
+2 bytes + 36 bytes - id of the room to copy
+2 bytes + <n> bytes - name of the copy, an empty name keeps the name of the original
+
+Tiles, entry, exit and spawners are copied, the copy is never the starting room. The result carries the id of
+the copy, which is sent right after it like a room list entry.
+*/
+func (h RoomCloneHandler) handle(packet *Packet) {
+	sourceID := packet.ReadUUID()
+	name := strings.TrimSpace(string(packet.ReadBytes(uint32(packet.ReadUint16()))))
+
+	connection := packet.Connection
+	if connection.player == nil || connection.player.role < ROLE_BUILDER {
+		sendCreatedRoom(connection, MsgRoomCloneRequest, nil, ROOM_UPDATE_ERROR_NOT_ALLOWED)
+		return
+	}
+	room, result := cloneRoom(sourceID, name)
+	sendCreatedRoom(connection, MsgRoomCloneRequest, room, result)
+}
+
+func cloneRoom(sourceID, name string) (*Room, uint8) {
+	source := ServerInstance.FindRoom(sourceID)
+	if source == nil {
+		return nil, ROOM_UPDATE_ERROR_UNKNOWN_ROOM
+	}
+	if name == "" {
+		name = source.Name
+	}
+	if !validRoomName(name) {
+		return nil, ROOM_UPDATE_ERROR_INVALID_NAME
+	}
+
+	room := NewRoom(source.Width, source.Height)
+	for x := range source.Tiles {
+		copy(room.Tiles[x], source.Tiles[x])
+	}
+	room.Name = name
+	room.Description = source.Description
+	room.Entry = source.Entry
+	room.Exit.LocationInRoom = source.Exit.LocationInRoom
+	room.Exit.Destinations = append([]uuid.UUID{}, source.Exit.Destinations...)
+	room.isActive = source.isActive
+	for _, spawner := range source.Spawners {
+		copied := NewSpawner(spawner.Template, spawner.Position, spawner.MaxPopulation, spawner.RespawnSeconds)
+		copied.Radius = spawner.Radius
+		copied.Waypoints = append([]Vector2{}, spawner.Waypoints...)
+		room.Spawners = append(room.Spawners, copied)
+	}
+	ServerInstance.roomList[room.ID.String()] = room
+	room.save()
+
+	log.Info().Str("room", room.ID.String()).Str("source", source.ID.String()).Msg("Room cloned")
+	return room, ROOM_UPDATE_OK
+}
+
+// sendCreatedRoom answers a create or clone request and sends the new room along, the editor keeps getting its deltas
+func sendCreatedRoom(connection *Connection, request PacketType, room *Room, result uint8) {
+	if room == nil {
+		sendRoomUpdateResult(connection, request, ROOM_FIELD_NONE, "", result)
+		return
+	}
+	sendRoomUpdateResult(connection, request, ROOM_FIELD_NONE, room.ID.String(), result)
+	if connection.telnet != nil || !room.fitsConnection(connection) {
+		return
+	}
+
+	connection.watch(room)
+	msg := NewPacket(MsgRoomCountResponse)
+	connection.writeRoom(msg, room)
+	sendMessageToConnection(connection, *msg)
+	if connection.wideCoordinates() {
+		connection.sendAllChunks(room)
+	}
+}
+
+type RoomDeleteHandler struct{}
+
+/*
+*****************************
+ROOM DELETE REQUEST STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+
+Exits leading to the room are removed and players inside are moved to the entry of the starting room.
+Every client is told the room is gone. Only admins can delete rooms.
+
+*****************************
+ROOM REMOVED STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+*/
+func (h RoomDeleteHandler) handle(packet *Packet) {
+	roomID := packet.ReadUUID()
+
+	connection := packet.Connection
+	result := ROOM_UPDATE_ERROR_NOT_ALLOWED
+	if connection.player != nil && connection.player.role >= ROLE_ADMIN {
+		result = deleteRoom(roomID)
+	}
+	sendRoomUpdateResult(connection, MsgRoomDeleteRequest, ROOM_FIELD_NONE, roomID, result)
+}
+
+func deleteRoom(roomID string) uint8 {
+	room := ServerInstance.FindRoom(roomID)
+	if room == nil {
+		return ROOM_UPDATE_ERROR_UNKNOWN_ROOM
+	}
+	if len(ServerInstance.roomList) == 1 {
+		return ROOM_UPDATE_ERROR_LAST_ROOM
+	}
+
+	delete(ServerInstance.roomList, roomID)
+	deleteRoomFile(roomID)
+
+	for _, other := range ServerInstance.roomList {
+		destinations := make([]uuid.UUID, 0, len(other.Exit.Destinations))
+		for _, d := range other.Exit.Destinations {
+			if d != room.ID {
+				destinations = append(destinations, d)
+			}
+		}
+		if len(destinations) != len(other.Exit.Destinations) {
+			other.Exit.Destinations = destinations
+			other.save()
+		}
+	}
+
+	if room.IsStartingRoom {
+		if starting := ServerInstance.findStartingRoom(); starting != nil {
+			starting.IsStartingRoom = true
+			starting.save()
+		}
+	}
+
+	for _, e := range room.entities {
+		if p, ok := e.(*Player); ok {
+			relocatePlayer(p, room)
+			continue
+		}
+		room.removeEntity(e, DESPAWN_REASON_REMOVED)
+	}
+
+	removed := NewPacket(MsgRoomRemoved)
+	removed.WriteString(roomID)
+	for _, connection := range ServerInstance.connectionsList {
+		delete(connection.watching, room.ID)
+		if p := connection.player; p != nil {
+			for _, id := range p.route {
+				if id == roomID {
+					p.clearPath()
+					break
+				}
+			}
+		}
+		if connection.telnet == nil {
+			sendMessageToConnection(connection, *removed)
+		}
+	}
+
+	log.Info().Str("room", roomID).Msg("Room deleted")
+	return ROOM_UPDATE_OK
+}
+
+// relocatePlayer moves a player out of a room that is going away, to the starting room when their client can show it
+func relocatePlayer(p *Player, from *Room) {
+	from.removeEntity(p, DESPAWN_REASON_LEFT)
+	p.clearPath()
+
+	target := ServerInstance.findStartingRoom()
+	if target == nil || !target.fitsConnection(p.connection) {
+		target = nil
+		for _, room := range ServerInstance.roomList {
+			if room.fitsConnection(p.connection) {
+				target = room
+				break
+			}
+		}
+	}
+	if target == nil {
+		// There is nowhere this client can go
+		if p.connection != nil {
+			p.connection.transport.Close()
+		}
+		return
+	}
+	placePlayerInRoom(p, target, target.Entry.LocationInRoom)
+	if p.connection != nil && p.connection.telnet != nil {
+		sendCommandOutput(p.connection, []string{"The room dissolves around you and you find yourself somewhere else."})
+	}
+}
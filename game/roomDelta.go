@@ -0,0 +1,151 @@
+package game
+
+import "github.com/google/uuid"
+
+// tilesSince returns the current state of every tile changed after the given revision, false when the
+// room no longer remembers that far back
+func (room *Room) tilesSince(revision uint64) ([]Tile, bool) {
+	if revision > room.revision {
+		return nil, false
+	}
+	if revision == room.revision {
+		return []Tile{}, true
+	}
+	if len(room.history) == 0 || room.history[0].Revision > revision+1 {
+		return nil, false
+	}
+
+	changed := make(map[Vector2]bool)
+	tiles := make([]Tile, 0)
+	for _, edit := range room.history {
+		if edit.Revision <= revision {
+			continue
+		}
+		for _, tile := range edit.Tiles {
+			if !changed[tile.Position] {
+				changed[tile.Position] = true
+				tiles = append(tiles, room.Tiles[tile.Position.X][tile.Position.Y])
+			}
+		}
+	}
+	return tiles, true
+}
+
+// isViewing reports whether the connection should hear about changes to the room
+func (connection *Connection) isViewing(room *Room) bool {
+	if player := connection.player; player != nil && player.currentRoom == room.ID {
+		return true
+	}
+	return connection.watching[room.ID]
+}
+
+// watch keeps the connection up to date on the room until it disconnects, it counts as an editor of the room from now on.
+// A builder that watches a room becomes an editor and is sent every tile from then on, not just what they explored.
+func (connection *Connection) watch(room *Room) {
+	if player := connection.player; player != nil && player.role >= ROLE_BUILDER {
+		connection.isEditor = true
+	}
+	if connection.watching == nil {
+		connection.watching = make(map[uuid.UUID]bool)
+	}
+	if !connection.watching[room.ID] {
+		connection.watching[room.ID] = true
+		room.broadcastPresence()
+	}
+}
+
+// knowsTile reports whether the client has the tile, players only have what they explored in the chunks they were sent
+func (connection *Connection) knowsTile(room *Room, pos Vector2) bool {
+	if include := connection.includeTile(room); include != nil && !include(pos.X, pos.Y) {
+		return false
+	}
+	player := connection.player
+	if player == nil || connection.isEditor || player.currentRoom != room.ID || player.chunks == nil {
+		return true
+	}
+	return player.chunks[chunkKey{room: room.ID, x: pos.X / ROOM_CHUNK_SIZE, y: pos.Y / ROOM_CHUNK_SIZE}]
+}
+
+/*
+*****************************
+ROOM TILE DELTA STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+8 bytes - uint64 room revision after the change
+2 bytes - uint16 number of tiles
+... 1 byte - tile type uint8 (max 255)
+... 1 byte - is passable byte
+... 1 byte - positionX uint8, uint16 from protocol version 3
+... 1 byte - positionY uint8, uint16 from protocol version 3
+*/
+func (connection *Connection) sendTileDelta(room *Room, tiles []Tile) {
+	wide := connection.wideCoordinates()
+	known := make([]Tile, 0, len(tiles))
+	for _, tile := range tiles {
+		if connection.knowsTile(room, tile.Position) {
+			known = append(known, tile)
+		}
+	}
+
+	pkt := NewPacket(MsgRoomTileDelta)
+	pkt.WriteString(room.ID.String())
+	pkt.WriteUint64(room.revision)
+	pkt.WriteUint16(uint16(len(known)))
+	for _, tile := range known {
+		pkt.WriteUint8(tile.Type).
+			WriteBool(tile.IsPassable).
+			WriteCoordinate(tile.Position.X, wide).
+			WriteCoordinate(tile.Position.Y, wide)
+	}
+	sendMessageToConnection(connection, *pkt)
+}
+
+// broadcastTileDelta tells every connection viewing the room, except the one that made the change, about the tiles
+func (room *Room) broadcastTileDelta(tiles []Tile, except *Connection) {
+	for _, connection := range ServerInstance.connectionsList {
+		if connection == except || connection.telnet != nil || !connection.isViewing(room) {
+			continue
+		}
+		connection.sendTileDelta(room, tiles)
+	}
+}
+
+type RoomResyncHandler struct{}
+
+/*
+*****************************
+ROOM RESYNC REQUEST STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+8 bytes - uint64 last revision the client has
+
+The client gets a tile delta with every tile that changed since, or the whole room followed by an empty
+delta when the room doesn't remember that far back. Either way the connection keeps getting deltas for the room.
+*/
+func (h RoomResyncHandler) handle(packet *Packet) {
+	connection := packet.Connection
+	room := ServerInstance.FindRoom(packet.ReadUUID())
+	revision := packet.ReadUint64()
+	if room == nil || !room.fitsConnection(connection) {
+		return
+	}
+	connection.watch(room)
+
+	if tiles, ok := room.tilesSince(revision); ok {
+		connection.sendTileDelta(room, tiles)
+		return
+	}
+
+	msg := NewPacket(MsgRoomCountResponse)
+	connection.writeRoom(msg, room)
+	sendMessageToConnection(connection, *msg)
+	if connection.wideCoordinates() {
+		if player := connection.player; player != nil && !connection.isEditor && player.currentRoom == room.ID {
+			player.resetChunks(room)
+			player.streamChunks(room)
+		} else {
+			connection.sendAllChunks(room)
+		}
+	}
+	connection.sendTileDelta(room, []Tile{})
+}
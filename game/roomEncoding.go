@@ -0,0 +1,224 @@
+package game
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	PROTOCOL_VERSION_1 = uint16(iota + 1)
+	// PROTOCOL_VERSION_2 sends rooms as a palette, run length encoded tile types and a passability bitset
+	PROTOCOL_VERSION_2
+	// PROTOCOL_VERSION_3 sends positions as uint16 and rooms as a header followed by chunks streamed around the player
+	PROTOCOL_VERSION_3
+	PROTOCOL_VERSION_LATEST = PROTOCOL_VERSION_3
+)
+
+// roomPaletteUnexplored marks tiles the player hasn't seen yet in a v2 room
+const roomPaletteUnexplored = uint8(255)
+
+/**
+Write the room in the compact v2 layout
+*/
+func (packet *Packet) WriteRoomDataV2(room *Room) {
+	packet.writeRoomDataV2(room, nil)
+}
+
+/**
+Write the room in the compact v2 layout, tiles the player hasn't explored are left out
+*/
+func (packet *Packet) WriteRoomDataV2ForPlayer(room *Room, player *Player) {
+	packet.writeRoomDataV2(room, func(x, y int) bool {
+		return player.hasExplored(room, x, y)
+	})
+}
+
+/*
+*****************************
+ROOM DATA V2 STRUCTURE
+*****************************
+2 bytes + <n> bytes - room id
+2 bytes + <n> bytes - room name
+2 bytes + <n> bytes - room description
+1 byte - room width uint8 (max 255)
+1 byte - room height uint8 (max 255)
+1 byte - number of tile types in the palette
+... 1 byte - tile type
+2 bytes - uint16 number of runs
+... 1 byte - palette index, 255 for tiles that haven't been explored
+... 1 - 3 bytes - uvarint run length
+<(width * height + 7) / 8> bytes - passability bitset, least significant bit first
+
+1 byte - room exit position X
+1 byte - room exit position Y
+1 byte - room entry position X
+1 byte - room entry position Y
+
+Runs and the bitset go through the tiles row by row, tile (x, y) is number y * width + x.
+*/
+func (packet *Packet) writeRoomDataV2(room *Room, include func(x, y int) bool) {
+	packet.WriteString(room.ID.String())
+	packet.WriteString(room.Name)
+	packet.WriteString(room.Description)
+	packet.WriteIntByte(room.Width)
+	packet.WriteIntByte(room.Height)
+
+	packet.writeTileRuns(room, 0, 0, room.Width, room.Height, include)
+
+	packet.WriteUint8(uint8(room.Exit.LocationInRoom.X))
+	packet.WriteUint8(uint8(room.Exit.LocationInRoom.Y))
+	packet.WriteUint8(uint8(room.Entry.LocationInRoom.X))
+	packet.WriteUint8(uint8(room.Entry.LocationInRoom.Y))
+}
+
+// writeTileRuns writes the palette, runs and passability bitset of the tiles in the given part of the room
+func (packet *Packet) writeTileRuns(room *Room, x0, y0, width, height int, include func(x, y int) bool) {
+	palette := make([]uint8, 0, 4)
+	// Indexed by tile type, looking up every tile in a map is the slowest part of encoding a room
+	var paletteIndex [256]uint8
+	var inPalette [256]bool
+	passable := make([]byte, (width*height+7)/8)
+	runs := make([]byte, 0, 64)
+	runCount := 0
+	varint := make([]byte, binary.MaxVarintLen32)
+
+	current, length := uint8(0), 0
+	flush := func() {
+		if length == 0 {
+			return
+		}
+		runs = append(runs, current)
+		n := binary.PutUvarint(varint, uint64(length))
+		runs = append(runs, varint[:n]...)
+		runCount++
+	}
+
+	for y := y0; y < y0+height; y++ {
+		for x := x0; x < x0+width; x++ {
+			index := roomPaletteUnexplored
+			if include == nil || include(x, y) {
+				tile := room.Tiles[x][y]
+				if !inPalette[tile.Type] {
+					paletteIndex[tile.Type] = uint8(len(palette))
+					inPalette[tile.Type] = true
+					palette = append(palette, tile.Type)
+				}
+				index = paletteIndex[tile.Type]
+				if tile.IsPassable {
+					bit := (y-y0)*width + (x - x0)
+					passable[bit/8] |= 1 << (bit % 8)
+				}
+			}
+
+			if index != current || length == 0 {
+				flush()
+				current, length = index, 0
+			}
+			length++
+		}
+	}
+	flush()
+
+	packet.WriteIntByte(len(palette))
+	for _, t := range palette {
+		packet.WriteUint8(t)
+	}
+	packet.WriteUint16(uint16(runCount))
+	packet.buffer = append(packet.buffer, runs...)
+	packet.buffer = append(packet.buffer, passable...)
+}
+
+// writeRoom writes the room in whichever encoding the connection negotiated, players only get what they explored
+func (connection *Connection) writeRoom(packet *Packet, room *Room) {
+	switch {
+	case connection.protocolVersion >= PROTOCOL_VERSION_3:
+		packet.writeRoomHeaderV3(room)
+	case connection.protocolVersion >= PROTOCOL_VERSION_2:
+		packet.writeRoomDataV2(room, connection.includeTile(room))
+	default:
+		packet.writeRoomData(room, connection.includeTile(room))
+	}
+}
+
+// includeTile returns which tiles of the room the connection may see, nil when it may see all of them
+func (connection *Connection) includeTile(room *Room) func(x, y int) bool {
+	player := connection.player
+	if player == nil || connection.isEditor {
+		return nil
+	}
+	return func(x, y int) bool {
+		return player.hasExplored(room, x, y)
+	}
+}
+
+type ProtocolVersionHandler struct{}
+
+/*
+*****************************
+PROTOCOL VERSION REQUEST STRUCTURE
+*****************************
+2 bytes - uint16 highest protocol version the client understands
+
+Response:
+2 bytes - uint16 protocol version used from now on, clients that never ask get version 1
+*/
+func (h ProtocolVersionHandler) handle(packet *Packet) {
+	version := packet.ReadUint16()
+	if version > PROTOCOL_VERSION_LATEST {
+		version = PROTOCOL_VERSION_LATEST
+	}
+	if version < PROTOCOL_VERSION_1 {
+		version = PROTOCOL_VERSION_1
+	}
+	packet.Connection.protocolVersion = version
+
+	response := NewPacket(MsgVersionResponse)
+	response.WriteUint16(version)
+	sendMessageToConnection(packet.Connection, *response)
+}
+
+// readTileRuns is the reverse of writeTileRuns, it fills the given part of the room with the tiles read from the packet
+func (packet *Packet) readTileRuns(room *Room, x0, y0, width, height int) error {
+	data := packet.buffer[packet.cursor:]
+	if len(data) < 1 || len(data) < 1+int(data[0])+2 {
+		return errors.New("tile runs are truncated")
+	}
+	palette := data[1 : 1+int(data[0])]
+	data = data[1+len(palette):]
+	runCount := int(binary.LittleEndian.Uint16(data))
+	data = data[2:]
+
+	indexes := make([]uint8, 0, width*height)
+	for i := 0; i < runCount; i++ {
+		if len(data) < 2 {
+			return errors.New("tile runs are truncated")
+		}
+		index := data[0]
+		length, n := binary.Uvarint(data[1:])
+		if n <= 0 || len(indexes)+int(length) > width*height {
+			return errors.New("tile run is malformed")
+		}
+		data = data[1+n:]
+		for j := 0; j < int(length); j++ {
+			indexes = append(indexes, index)
+		}
+	}
+	if len(indexes) != width*height || len(data) < (width*height+7)/8 {
+		return errors.New("tile runs don't cover the area")
+	}
+	passable := data[:(width*height+7)/8]
+
+	for i, index := range indexes {
+		if index == roomPaletteUnexplored || int(index) >= len(palette) {
+			continue
+		}
+		x, y := x0+i%width, y0+i/width
+		room.Tiles[x][y] = Tile{
+			Type:       palette[index],
+			IsPassable: passable[i/8]&(1<<(i%8)) != 0,
+			Position:   Vector2{x, y},
+		}
+	}
+	packet.cursor = uint32(len(packet.buffer) - len(data) + len(passable))
+	return nil
+}
@@ -0,0 +1,132 @@
+package game
+
+import "time"
+
+// inBounds checks whether the given position lies inside of the room grid
+func (room *Room) inBounds(pos Vector2) bool {
+	return pos.X >= 0 && pos.Y >= 0 && pos.X < room.Width && pos.Y < room.Height
+}
+
+// entityAt returns the first entity standing on the given position or nil
+func (room *Room) entityAt(pos Vector2) entity {
+	for _, e := range room.entities {
+		if e.getPosition() == pos {
+			return e
+		}
+	}
+	return nil
+}
+
+// blockingEntityAt returns an entity standing on the given position that nothing else can pass through
+func (room *Room) blockingEntityAt(pos Vector2) entity {
+	for _, e := range room.entities {
+		if !e.ispassable() && e.getPosition() == pos {
+			return e
+		}
+	}
+	return nil
+}
+
+// isWalkable checks whether an entity can stand on the given position
+func (room *Room) isWalkable(pos Vector2) bool {
+	if !room.inBounds(pos) || !room.Tiles[pos.X][pos.Y].IsPassable {
+		return false
+	}
+	return room.blockingEntityAt(pos) == nil
+}
+
+// players returns all players that are currently inside of the room
+func (room *Room) players() []*Player {
+	list := make([]*Player, 0)
+	for _, e := range room.entities {
+		if p, ok := e.(*Player); ok {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// broadcast sends the packet to every player in the room
+func (room *Room) broadcast(packet *Packet) {
+	for _, p := range room.players() {
+		if p.connection != nil {
+			sendMessageToConnection(p.connection, *packet)
+		}
+	}
+}
+
+// addEntity places the entity in the room and tells the occupants about it
+func (room *Room) addEntity(e entity) {
+	if room.entities == nil {
+		room.entities = make(map[int64]entity)
+	}
+	room.entities[e.getID()] = e
+
+	room.broadcastPositions(func(wide bool) *Packet {
+		pkt := NewPacket(MsgEntitySpawn)
+		pkt.writeEntityData(e, wide)
+		return pkt
+	})
+}
+
+// removeEntity takes the entity out of the room and tells the remaining occupants about it
+func (room *Room) removeEntity(e entity, reason uint8) {
+	if _, found := room.entities[e.getID()]; !found {
+		return
+	}
+	delete(room.entities, e.getID())
+
+	if npc, ok := e.(*NPC); ok && npc.spawner != nil {
+		npc.spawner.onDespawn(npc, time.Now())
+	}
+
+	pkt := NewPacket(MsgEntityDespawn)
+	pkt.WriteUint64(uint64(e.getID()))
+	pkt.WriteString(room.ID.String())
+	pkt.WriteUint8(reason)
+	room.broadcast(pkt)
+}
+
+// moveEntity moves the entity to a new position if it is walkable and tells the occupants about it
+func (room *Room) moveEntity(e entity, pos Vector2) bool {
+	if !room.isWalkable(pos) {
+		return false
+	}
+
+	switch v := e.(type) {
+	case *Player:
+		v.position = pos
+		defer v.updateView(room)
+		defer v.streamChunks(room)
+	case *NPC:
+		v.position = pos
+	default:
+		return false
+	}
+
+	room.broadcastPositions(func(wide bool) *Packet {
+		pkt := NewPacket(MsgEntityMove)
+		pkt.WriteUint64(uint64(e.getID()))
+		pkt.WriteCoordinate(pos.X, wide)
+		pkt.WriteCoordinate(pos.Y, wide)
+		return pkt
+	})
+	return true
+}
+
+// tick advances all time based room logic
+func (room *Room) tick(now time.Time) {
+	room.expireLocks(now)
+	for _, spawner := range room.Spawners {
+		spawner.tick(room, now)
+	}
+
+	for _, e := range room.entities {
+		switch v := e.(type) {
+		case *NPC:
+			v.think(room, now)
+		case *Player:
+			v.followPath(room, now)
+		}
+	}
+}
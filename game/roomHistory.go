@@ -0,0 +1,249 @@
+package game
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// roomHistoryLimit is how many revisions a room remembers for resyncs and reverts
+	roomHistoryLimit = 100
+	// roomUndoLimit is how many edits can be undone, and redone, in a single room
+	roomUndoLimit = 50
+	// roomEditTileLimit is how many tiles the history, the undo and the redo edits of a room hold at most each,
+	// counting the tiles before each edit too. Rooms are saved with all of them after every edit.
+	roomEditTileLimit = 1 << 16
+)
+
+// roomEdit is a batch of tiles that changed together and moved the room to Revision. Previous holds the tiles
+// as they were before, in the same order, so the edit can be taken back.
+type roomEdit struct {
+	Revision uint64 `json:"revision"`
+	Author   string `json:"author"`
+	Tiles    []Tile `json:"tiles"`
+	Previous []Tile `json:"previous"`
+}
+
+// applyTiles writes the tiles into the room as a single revision and records it in the history, the tiles have to
+// pass validateTiles first. A position that is in the batch more than once is recorded once with the last tile,
+// so undoing the edit puts back what was there before the whole batch.
+func (room *Room) applyTiles(tiles []Tile, author string) roomEdit {
+	tiles = lastTilePerPosition(tiles)
+	previous := make([]Tile, 0, len(tiles))
+	for _, tile := range tiles {
+		previous = append(previous, room.Tiles[tile.Position.X][tile.Position.Y])
+		room.Tiles[tile.Position.X][tile.Position.Y] = tile
+	}
+	room.revision++
+
+	edit := roomEdit{Revision: room.revision, Author: author, Tiles: tiles, Previous: previous}
+	room.history = trimEdits(append(room.history, edit), roomHistoryLimit)
+	return edit
+}
+
+// lastTilePerPosition drops every tile that a later tile in the batch overwrites, keeping the order of the rest
+func lastTilePerPosition(tiles []Tile) []Tile {
+	last := make(map[Vector2]int, len(tiles))
+	for i, tile := range tiles {
+		last[tile.Position] = i
+	}
+	if len(last) == len(tiles) {
+		return tiles
+	}
+
+	kept := make([]Tile, 0, len(last))
+	for i, tile := range tiles {
+		if last[tile.Position] == i {
+			kept = append(kept, tile)
+		}
+	}
+	return kept
+}
+
+// publishTiles saves the room and tells everyone viewing it about the tiles, except the connection that sent them
+func (room *Room) publishTiles(tiles []Tile, except *Connection) {
+	room.save()
+	room.broadcastTileDelta(tiles, except)
+	// Walls may have come down or gone up in front of someone
+	for _, p := range room.players() {
+		p.updateView(room)
+	}
+}
+
+// editTiles applies an edit that can be undone, anything that was undone before can no longer be redone
+func (room *Room) editTiles(tiles []Tile, author string, except *Connection) uint64 {
+	edit := room.applyTiles(tiles, author)
+	room.undo = pushEdit(room.undo, edit)
+	room.redo = nil
+	room.publishTiles(edit.Tiles, except)
+	return edit.Revision
+}
+
+// undoEdit takes back the last edit made in the room as a new revision, unless someone else holds a lock on its tiles
+func (room *Room) undoEdit(connection *Connection) (uint64, uint8) {
+	if len(room.undo) == 0 {
+		return room.revision, ROOM_UPDATE_ERROR_NOTHING_TO_UNDO
+	}
+	edit := room.undo[len(room.undo)-1]
+	if result, _ := room.validateTiles(edit.Previous); result != ROOM_UPDATE_OK {
+		return room.revision, result
+	}
+	now := time.Now()
+	if room.anyLockedFor(connection, edit.Previous, now) {
+		return room.revision, ROOM_UPDATE_ERROR_LOCKED
+	}
+	room.undo = room.undo[:len(room.undo)-1]
+	author := connection.editorName()
+	room.renewLocks(connection, now)
+
+	applied := room.applyTiles(edit.Previous, author)
+	room.redo = pushEdit(room.redo, edit)
+	room.publishTiles(edit.Previous, nil)
+	log.Info().Str("room", room.ID.String()).Str("author", author).Uint64("undone", edit.Revision).Msg("Room edit undone")
+	return applied.Revision, ROOM_UPDATE_OK
+}
+
+// redoEdit applies the last undone edit again as a new revision, unless someone else holds a lock on its tiles
+func (room *Room) redoEdit(connection *Connection) (uint64, uint8) {
+	if len(room.redo) == 0 {
+		return room.revision, ROOM_UPDATE_ERROR_NOTHING_TO_REDO
+	}
+	edit := room.redo[len(room.redo)-1]
+	if result, _ := room.validateTiles(edit.Tiles); result != ROOM_UPDATE_OK {
+		return room.revision, result
+	}
+	now := time.Now()
+	if room.anyLockedFor(connection, edit.Tiles, now) {
+		return room.revision, ROOM_UPDATE_ERROR_LOCKED
+	}
+	room.redo = room.redo[:len(room.redo)-1]
+	author := connection.editorName()
+	room.renewLocks(connection, now)
+
+	applied := room.applyTiles(edit.Tiles, author)
+	room.undo = pushEdit(room.undo, edit)
+	room.publishTiles(edit.Tiles, nil)
+	log.Info().Str("room", room.ID.String()).Str("author", author).Uint64("redone", edit.Revision).Msg("Room edit redone")
+	return applied.Revision, ROOM_UPDATE_OK
+}
+
+// revertTo puts every tile changed after the given revision back the way it was, as a single edit that can be undone
+func (room *Room) revertTo(revision uint64, author string) (uint64, uint8) {
+	if revision >= room.revision {
+		return room.revision, ROOM_UPDATE_ERROR_UNKNOWN_REVISION
+	}
+	if len(room.history) == 0 || room.history[0].Revision > revision+1 {
+		return room.revision, ROOM_UPDATE_ERROR_UNKNOWN_REVISION
+	}
+
+	// Walking back from the newest edit leaves the oldest previous tile in place for every position
+	restored := make(map[Vector2]Tile)
+	order := make([]Vector2, 0)
+	for i := len(room.history) - 1; i >= 0 && room.history[i].Revision > revision; i-- {
+		for _, tile := range room.history[i].Previous {
+			if _, found := restored[tile.Position]; !found {
+				order = append(order, tile.Position)
+			}
+			restored[tile.Position] = tile
+		}
+	}
+
+	tiles := make([]Tile, 0, len(order))
+	for _, pos := range order {
+		tiles = append(tiles, restored[pos])
+	}
+	if result, _ := room.validateTiles(tiles); result != ROOM_UPDATE_OK {
+		return room.revision, result
+	}
+	applied := room.editTiles(tiles, author, nil)
+	log.Info().Str("room", room.ID.String()).Str("author", author).Uint64("revision", revision).Msg("Room reverted")
+	return applied, ROOM_UPDATE_OK
+}
+
+func pushEdit(stack []roomEdit, edit roomEdit) []roomEdit {
+	return trimEdits(append(stack, edit), roomUndoLimit)
+}
+
+// trimEdits drops the oldest edits until at most limit edits are left and they hold no more than roomEditTileLimit
+// tiles. An edit too large to fit on its own isn't kept at all.
+func trimEdits(edits []roomEdit, limit int) []roomEdit {
+	tiles := 0
+	for i := len(edits) - 1; i >= 0; i-- {
+		tiles += len(edits[i].Tiles) + len(edits[i].Previous)
+		if len(edits)-i > limit || tiles > roomEditTileLimit {
+			return edits[i+1:]
+		}
+	}
+	return edits
+}
+
+// editorName is who edits made over the connection are recorded as
+func (connection *Connection) editorName() string {
+	if connection.player != nil {
+		return connection.player.name
+	}
+	return connection.transport.RemoteAddr().String()
+}
+
+type RoomUndoHandler struct{}
+
+/*
+*****************************
+ROOM UNDO REQUEST STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+
+Only builders can undo. The result is followed by ROOM HISTORY RESULT, the tiles go out as a delta to
+everyone viewing the room, including the builder.
+*/
+func (h RoomUndoHandler) handle(packet *Packet) {
+	handleRoomHistoryRequest(packet, MsgRoomUndoRequest, (*Room).undoEdit)
+}
+
+type RoomRedoHandler struct{}
+
+/*
+*****************************
+ROOM REDO REQUEST STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+
+Works like the undo request.
+*/
+func (h RoomRedoHandler) handle(packet *Packet) {
+	handleRoomHistoryRequest(packet, MsgRoomRedoRequest, (*Room).redoEdit)
+}
+
+/*
+*****************************
+ROOM HISTORY RESULT STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+8 bytes - uint64 room revision
+2 bytes - uint16 number of edits that can be undone
+2 bytes - uint16 number of edits that can be redone
+*/
+func handleRoomHistoryRequest(packet *Packet, request PacketType, apply func(*Room, *Connection) (uint64, uint8)) {
+	connection := packet.Connection
+	roomID := packet.ReadUUID()
+	room := ServerInstance.FindRoom(roomID)
+
+	result := ROOM_UPDATE_ERROR_UNKNOWN_ROOM
+	if connection.player == nil || connection.player.role < ROLE_BUILDER {
+		result = ROOM_UPDATE_ERROR_NOT_ALLOWED
+	} else if room != nil {
+		_, result = apply(room, connection)
+	}
+	sendRoomUpdateResult(connection, request, ROOM_FIELD_NONE, roomID, result)
+	if room == nil || result == ROOM_UPDATE_ERROR_NOT_ALLOWED {
+		return
+	}
+
+	pkt := NewPacket(MsgRoomHistory)
+	pkt.WriteString(room.ID.String())
+	pkt.WriteUint64(room.revision)
+	pkt.WriteUint16(uint16(len(room.undo)))
+	pkt.WriteUint16(uint16(len(room.redo)))
+	sendMessageToConnection(connection, *pkt)
+}
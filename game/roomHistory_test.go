@@ -0,0 +1,122 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func historyRoom() *Room {
+	ServerInstance = &Server{
+		config:   &serverConfig{},
+		roomList: map[string]*Room{},
+		tileTypes: map[uint8]*tileDefinition{
+			TILE_TYPE_WALL:  {ID: TILE_TYPE_WALL, MovementCost: 1},
+			TILE_TYPE_DIRT:  {ID: TILE_TYPE_DIRT, Passable: true, MovementCost: 1},
+			TILE_TYPE_WATER: {ID: TILE_TYPE_WATER, Passable: true, MovementCost: 3},
+		},
+	}
+	room := NewRoom(8, 8)
+	for x := 0; x < room.Width; x++ {
+		for y := 0; y < room.Height; y++ {
+			room.Tiles[x][y] = newTile(TILE_TYPE_DIRT, Vector2{x, y})
+		}
+	}
+	return room
+}
+
+func expectTile(t *testing.T, room *Room, pos Vector2, tileType uint8, step string) {
+	t.Helper()
+	if got := room.Tiles[pos.X][pos.Y].Type; got != tileType {
+		t.Fatalf("Expected tile %v to be of type %d after %s but it was %d", pos, tileType, step, got)
+	}
+}
+
+func TestRepeatedPositionsInAnEdit(t *testing.T) {
+	room := historyRoom()
+	builder := &Connection{player: &Player{name: "builder", role: ROLE_BUILDER}}
+	tiles := []Tile{
+		newTile(TILE_TYPE_WALL, Vector2{2, 2}),
+		newTile(TILE_TYPE_WALL, Vector2{3, 3}),
+		newTile(TILE_TYPE_WATER, Vector2{2, 2}),
+	}
+	if result, pos := room.validateTiles(tiles); result != ROOM_UPDATE_OK {
+		t.Fatalf("Expected the edit to be valid but got %d at %v", result, pos)
+	}
+
+	room.editTiles(tiles, "builder", nil)
+	expectTile(t, room, Vector2{2, 2}, TILE_TYPE_WATER, "the edit")
+	expectTile(t, room, Vector2{3, 3}, TILE_TYPE_WALL, "the edit")
+	if edit := room.history[len(room.history)-1]; len(edit.Tiles) != 2 || len(edit.Previous) != 2 {
+		t.Fatalf("Expected the edit to record every position once but it has %d tiles and %d previous tiles", len(edit.Tiles), len(edit.Previous))
+	}
+
+	if _, result := room.undoEdit(builder); result != ROOM_UPDATE_OK {
+		t.Fatalf("Expected the undo to succeed but got %d", result)
+	}
+	expectTile(t, room, Vector2{2, 2}, TILE_TYPE_DIRT, "the undo")
+	expectTile(t, room, Vector2{3, 3}, TILE_TYPE_DIRT, "the undo")
+
+	if _, result := room.redoEdit(builder); result != ROOM_UPDATE_OK {
+		t.Fatalf("Expected the redo to succeed but got %d", result)
+	}
+	expectTile(t, room, Vector2{2, 2}, TILE_TYPE_WATER, "the redo")
+	expectTile(t, room, Vector2{3, 3}, TILE_TYPE_WALL, "the redo")
+
+	if _, result := room.revertTo(0, "admin"); result != ROOM_UPDATE_OK {
+		t.Fatalf("Expected the revert to succeed but got %d", result)
+	}
+	expectTile(t, room, Vector2{2, 2}, TILE_TYPE_DIRT, "the revert")
+	expectTile(t, room, Vector2{3, 3}, TILE_TYPE_DIRT, "the revert")
+}
+
+func TestUndoAndRedoRespectLocks(t *testing.T) {
+	room := historyRoom()
+	builder := &Connection{player: &Player{name: "builder", role: ROLE_BUILDER}}
+	other := &Connection{player: &Player{name: "other", role: ROLE_BUILDER}}
+	room.editTiles([]Tile{newTile(TILE_TYPE_WATER, Vector2{2, 2})}, "builder", nil)
+
+	room.locks = append(room.locks, &roomLock{owner: other, region: roomRegion{X: 2, Y: 2, Width: 1, Height: 1}, expires: time.Now().Add(roomLockLease)})
+	if _, result := room.undoEdit(builder); result != ROOM_UPDATE_ERROR_LOCKED {
+		t.Fatalf("Expected the undo of a locked tile to be rejected but got %d", result)
+	}
+	expectTile(t, room, Vector2{2, 2}, TILE_TYPE_WATER, "the rejected undo")
+
+	if _, result := room.undoEdit(other); result != ROOM_UPDATE_OK {
+		t.Fatalf("Expected the lock owner to be able to undo but got %d", result)
+	}
+	if _, result := room.redoEdit(builder); result != ROOM_UPDATE_ERROR_LOCKED {
+		t.Fatalf("Expected the redo of a locked tile to be rejected but got %d", result)
+	}
+	expectTile(t, room, Vector2{2, 2}, TILE_TYPE_DIRT, "the rejected redo")
+}
+
+func TestHistoryIsCappedByTiles(t *testing.T) {
+	room := historyRoom()
+	room.Width, room.Height = 300, 300
+	room.Tiles = make([][]Tile, room.Width)
+	for x := range room.Tiles {
+		room.Tiles[x] = make([]Tile, room.Height)
+	}
+
+	// Every edit changes a whole row, so only a few of them fit in the tile limit
+	for y := 0; y < 250; y++ {
+		row := make([]Tile, 0, room.Width)
+		for x := 0; x < room.Width; x++ {
+			row = append(row, newTile(TILE_TYPE_DIRT, Vector2{x, y}))
+		}
+		room.editTiles(row, "builder", nil)
+	}
+
+	for _, edits := range [][]roomEdit{room.history, room.undo} {
+		tiles := 0
+		for _, edit := range edits {
+			tiles += len(edit.Tiles) + len(edit.Previous)
+		}
+		if len(edits) == 0 || tiles > roomEditTileLimit {
+			t.Fatalf("Expected the newest edits to be kept within %d tiles but %d edits hold %d tiles", roomEditTileLimit, len(edits), tiles)
+		}
+	}
+	if newest := room.history[len(room.history)-1]; newest.Revision != room.revision {
+		t.Fatalf("Expected the newest edit to be kept but the history ends at revision %d", newest.Revision)
+	}
+}
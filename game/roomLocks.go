@@ -0,0 +1,316 @@
+package game
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// roomLockLease is how long a lock lasts unless its owner asks for it again or keeps editing under it
+const roomLockLease = 60 * time.Second
+
+const (
+	ROOM_LOCK_ACQUIRE = uint8(iota)
+	ROOM_LOCK_RELEASE
+)
+
+// roomRegion is a rectangle of tiles, a region without a size covers the whole room
+type roomRegion struct {
+	X, Y, Width, Height int
+}
+
+func (r roomRegion) whole() bool {
+	return r.Width <= 0 || r.Height <= 0
+}
+
+func (r roomRegion) contains(pos Vector2) bool {
+	return r.whole() || (pos.X >= r.X && pos.Y >= r.Y && pos.X < r.X+r.Width && pos.Y < r.Y+r.Height)
+}
+
+func (r roomRegion) overlaps(other roomRegion) bool {
+	if r.whole() || other.whole() {
+		return true
+	}
+	return r.X < other.X+other.Width && other.X < r.X+r.Width && r.Y < other.Y+other.Height && other.Y < r.Y+r.Height
+}
+
+// roomLock keeps everyone but its owner from editing tiles in the region until it expires
+type roomLock struct {
+	owner   *Connection
+	region  roomRegion
+	expires time.Time
+}
+
+// lockedFor returns a lock held by someone else that covers the position, nil when the connection may edit it
+func (room *Room) lockedFor(connection *Connection, pos Vector2, now time.Time) *roomLock {
+	for _, lock := range room.locks {
+		if lock.owner != connection && now.Before(lock.expires) && lock.region.contains(pos) {
+			return lock
+		}
+	}
+	return nil
+}
+
+// anyLockedFor reports whether someone else holds a lock on any of the tiles
+func (room *Room) anyLockedFor(connection *Connection, tiles []Tile, now time.Time) bool {
+	for _, tile := range tiles {
+		if room.lockedFor(connection, tile.Position, now) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// acquireLock locks the region for the connection, asking again for a region it already holds renews the lease.
+// Admins break the locks of others that are in the way.
+func (room *Room) acquireLock(connection *Connection, region roomRegion, now time.Time) uint8 {
+	if !region.whole() && (region.X < 0 || region.Y < 0 || region.X+region.Width > room.Width || region.Y+region.Height > room.Height) {
+		return ROOM_UPDATE_ERROR_OUT_OF_BOUNDS
+	}
+	room.expireLocks(now)
+	admin := connection.player != nil && connection.player.role >= ROLE_ADMIN
+	kept := room.locks[:0]
+	for _, lock := range room.locks {
+		if lock.owner != connection && lock.region.overlaps(region) {
+			if !admin {
+				return ROOM_UPDATE_ERROR_LOCKED
+			}
+			log.Info().Str("room", room.ID.String()).Str("editor", lock.owner.editorName()).Str("admin", connection.editorName()).Msg("Room lock broken")
+			continue
+		}
+		kept = append(kept, lock)
+	}
+	broken := len(kept) != len(room.locks)
+	room.locks = kept
+
+	for _, lock := range room.locks {
+		if lock.owner == connection && lock.region == region {
+			lock.expires = now.Add(roomLockLease)
+			if broken {
+				room.broadcastPresence()
+			}
+			return ROOM_UPDATE_OK
+		}
+	}
+	room.locks = append(room.locks, &roomLock{owner: connection, region: region, expires: now.Add(roomLockLease)})
+	log.Debug().Str("room", room.ID.String()).Str("editor", connection.editorName()).Msg("Room lock acquired")
+	room.broadcastPresence()
+	return ROOM_UPDATE_OK
+}
+
+// releaseLocks drops every lock the connection holds in the room
+func (room *Room) releaseLocks(connection *Connection) {
+	if room.dropLocks(connection) {
+		room.broadcastPresence()
+	}
+}
+
+func (room *Room) dropLocks(connection *Connection) bool {
+	kept := room.locks[:0]
+	for _, lock := range room.locks {
+		if lock.owner != connection {
+			kept = append(kept, lock)
+		}
+	}
+	dropped := len(kept) != len(room.locks)
+	room.locks = kept
+	return dropped
+}
+
+// renewLocks extends every lock of the connection in the room, editing under a lock keeps it alive
+func (room *Room) renewLocks(connection *Connection, now time.Time) {
+	for _, lock := range room.locks {
+		if lock.owner == connection {
+			lock.expires = now.Add(roomLockLease)
+		}
+	}
+}
+
+// expireLocks drops locks whose lease ran out
+func (room *Room) expireLocks(now time.Time) {
+	kept := room.locks[:0]
+	for _, lock := range room.locks {
+		if now.Before(lock.expires) {
+			kept = append(kept, lock)
+		}
+	}
+	if len(kept) != len(room.locks) {
+		room.locks = kept
+		room.broadcastPresence()
+	}
+}
+
+// editors returns every connection editing the room, in the order they connected
+func (room *Room) editors() []*Connection {
+	list := make([]*Connection, 0)
+	for _, connection := range ServerInstance.connectionsList {
+		if connection.watching[room.ID] {
+			list = append(list, connection)
+		}
+	}
+	return list
+}
+
+/*
+*****************************
+ROOM PRESENCE STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+2 bytes - uint16 number of editors
+... 2 bytes + <n> bytes - editor name
+... 2 bytes - uint16 number of locks the editor holds
+... ... 2 bytes - region X uint16
+... ... 2 bytes - region Y uint16
+... ... 2 bytes - region width uint16, 0 when the whole room is locked
+... ... 2 bytes - region height uint16, 0 when the whole room is locked
+... ... 2 bytes - uint16 seconds left on the lease
+
+Everyone viewing the room gets this whenever an editor comes or goes, or a lock changes.
+*/
+func (room *Room) broadcastPresence() {
+	editors := room.editors()
+	now := time.Now()
+
+	pkt := NewPacket(MsgRoomPresence)
+	pkt.WriteString(room.ID.String())
+	pkt.WriteUint16(uint16(len(editors)))
+	for _, editor := range editors {
+		pkt.WriteString(editor.editorName())
+		locks := make([]*roomLock, 0)
+		for _, lock := range room.locks {
+			if lock.owner == editor {
+				locks = append(locks, lock)
+			}
+		}
+		pkt.WriteUint16(uint16(len(locks)))
+		for _, lock := range locks {
+			region := lock.region
+			if region.whole() {
+				region = roomRegion{}
+			}
+			pkt.WriteUint16(uint16(region.X)).
+				WriteUint16(uint16(region.Y)).
+				WriteUint16(uint16(region.Width)).
+				WriteUint16(uint16(region.Height)).
+				WriteUint16(uint16(lock.expires.Sub(now) / time.Second))
+		}
+	}
+
+	for _, connection := range ServerInstance.connectionsList {
+		if connection.telnet == nil && connection.isViewing(room) {
+			sendMessageToConnection(connection, *pkt)
+		}
+	}
+}
+
+// leaveEditing releases everything the connection held, it is called once the connection is gone
+func (connection *Connection) leaveEditing() {
+	watching := connection.watching
+	connection.watching = nil
+	for id := range watching {
+		if room := ServerInstance.FindRoom(id.String()); room != nil {
+			room.dropLocks(connection)
+			room.broadcastPresence()
+		}
+	}
+}
+
+type RoomLockHandler struct{}
+
+/*
+*****************************
+ROOM LOCK REQUEST STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+1 byte - action (0 - acquire or renew, 1 - release every lock held in the room)
+... acquire:
+... 1 byte - position X, uint16 from protocol version 3
+... 1 byte - position Y, uint16 from protocol version 3
+... 1 byte - width, uint16 from protocol version 3, 0 locks the whole room
+... 1 byte - height, uint16 from protocol version 3, 0 locks the whole room
+
+Locks last 60 seconds unless they are renewed or the owner edits tiles under them. Locking makes the
+connection an editor of the room. Only builders can lock, an admin breaks any lock in the way.
+The answer is a room update result.
+*/
+func (h RoomLockHandler) handle(packet *Packet) {
+	connection := packet.Connection
+	roomID := packet.ReadUUID()
+	action := packet.ReadUint8()
+	room, result := builderRoom(connection, roomID)
+
+	switch action {
+	case ROOM_LOCK_ACQUIRE:
+		wide := connection.wideCoordinates()
+		region := roomRegion{
+			X:      packet.ReadCoordinate(wide),
+			Y:      packet.ReadCoordinate(wide),
+			Width:  packet.ReadCoordinate(wide),
+			Height: packet.ReadCoordinate(wide),
+		}
+		if room != nil {
+			connection.watch(room)
+			result = room.acquireLock(connection, region, time.Now())
+		}
+	case ROOM_LOCK_RELEASE:
+		if room != nil {
+			room.releaseLocks(connection)
+			result = ROOM_UPDATE_OK
+		}
+	default:
+		result = ROOM_UPDATE_ERROR_UNKNOWN_FIELD
+	}
+	sendRoomUpdateResult(connection, MsgRoomLockRequest, ROOM_FIELD_NONE, roomID, result)
+}
+
+/*
+*****************************
+ROOM EDIT CONFLICT STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+8 bytes - uint64 current room revision
+2 bytes - uint16 number of tiles
+... 1 byte - tile type uint8 (max 255)
+... 1 byte - is passable byte
+... 1 byte - positionX uint8, uint16 from protocol version 3
+... 1 byte - positionY uint8, uint16 from protocol version 3
+
+Sent instead of the ack when an edit was based on an old revision and touches tiles that changed since,
+the tiles are the current state of every tile in the rejected edit.
+*/
+func sendEditConflict(connection *Connection, room *Room, tiles []Tile) {
+	wide := connection.wideCoordinates()
+	pkt := NewPacket(MsgRoomEditConflict)
+	pkt.WriteString(room.ID.String())
+	pkt.WriteUint64(room.revision)
+	pkt.WriteUint16(uint16(len(tiles)))
+	for _, tile := range tiles {
+		current := room.Tiles[tile.Position.X][tile.Position.Y]
+		pkt.WriteUint8(current.Type).
+			WriteBool(current.IsPassable).
+			WriteCoordinate(tile.Position.X, wide).
+			WriteCoordinate(tile.Position.Y, wide)
+	}
+	sendMessageToConnection(connection, *pkt)
+}
+
+// isStale reports whether any of the tiles changed after the revision the editor based the edit on
+func (room *Room) isStale(tiles []Tile, base uint64) bool {
+	if base == room.revision {
+		return false
+	}
+	changed, ok := room.tilesSince(base)
+	if !ok {
+		return true
+	}
+	positions := make(map[Vector2]bool, len(changed))
+	for _, tile := range changed {
+		positions[tile.Position] = true
+	}
+	for _, tile := range tiles {
+		if positions[tile.Position] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package game
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ROOM_UPDATE_OK = uint8(iota)
+	ROOM_UPDATE_ERROR_UNKNOWN_ROOM
+	ROOM_UPDATE_ERROR_INVALID_NAME
+	ROOM_UPDATE_ERROR_INVALID_DESCRIPTION
+	ROOM_UPDATE_ERROR_OUT_OF_BOUNDS
+	ROOM_UPDATE_ERROR_NOT_PASSABLE
+	ROOM_UPDATE_ERROR_UNKNOWN_DESTINATION
+	ROOM_UPDATE_ERROR_UNKNOWN_FIELD
+	ROOM_UPDATE_ERROR_INVALID_SIZE
+	ROOM_UPDATE_ERROR_LAST_ROOM
+	ROOM_UPDATE_ERROR_NOTHING_TO_UNDO
+	ROOM_UPDATE_ERROR_NOTHING_TO_REDO
+	ROOM_UPDATE_ERROR_UNKNOWN_REVISION
+	ROOM_UPDATE_ERROR_NOT_ALLOWED
+	ROOM_UPDATE_ERROR_LOCKED
+	ROOM_UPDATE_ERROR_UNKNOWN_TILE_TYPE
+	ROOM_UPDATE_ERROR_UNREACHABLE
+	ROOM_UPDATE_ERROR_UNKNOWN_PREFAB
+)
+
+const (
+	ROOM_FIELD_NONE = uint8(iota)
+	ROOM_FIELD_ENTRY
+	ROOM_FIELD_EXIT
+	ROOM_FIELD_DESTINATIONS
+	ROOM_FIELD_STARTING
+)
+
+const (
+	maxRoomNameLength        = 64
+	maxRoomDescriptionLength = 1024
+)
+
+var roomUpdateResultText = map[uint8]string{
+	ROOM_UPDATE_OK:                        "Room updated.",
+	ROOM_UPDATE_ERROR_UNKNOWN_ROOM:        "There is no such room.",
+	ROOM_UPDATE_ERROR_INVALID_NAME:        "Room names can't be empty or longer than 64 characters.",
+	ROOM_UPDATE_ERROR_INVALID_DESCRIPTION: "Room descriptions can't be longer than 1024 characters.",
+	ROOM_UPDATE_ERROR_OUT_OF_BOUNDS:       "That position is outside of the room.",
+	ROOM_UPDATE_ERROR_NOT_PASSABLE:        "Nobody can stand on that tile.",
+	ROOM_UPDATE_ERROR_UNKNOWN_DESTINATION: "One of the destinations doesn't exist.",
+	ROOM_UPDATE_ERROR_UNKNOWN_FIELD:       "That field can't be changed.",
+	ROOM_UPDATE_ERROR_INVALID_SIZE:        "That room size is outside of the configured bounds.",
+	ROOM_UPDATE_ERROR_LAST_ROOM:           "The last room can't be deleted.",
+	ROOM_UPDATE_ERROR_NOTHING_TO_UNDO:     "There is nothing to undo in this room.",
+	ROOM_UPDATE_ERROR_NOTHING_TO_REDO:     "There is nothing to redo in this room.",
+	ROOM_UPDATE_ERROR_UNKNOWN_REVISION:    "The room doesn't remember that revision.",
+	ROOM_UPDATE_ERROR_NOT_ALLOWED:         "You aren't allowed to do that.",
+	ROOM_UPDATE_ERROR_LOCKED:              "Someone else is editing that part of the room.",
+	ROOM_UPDATE_ERROR_UNKNOWN_TILE_TYPE:   "That tile type doesn't exist.",
+	ROOM_UPDATE_ERROR_UNREACHABLE:         "The exit can't be reached from the entry that way.",
+	ROOM_UPDATE_ERROR_UNKNOWN_PREFAB:      "There is no such prefab or anchor.",
+}
+
+/*
+*****************************
+ROOM UPDATE RESULT STRUCTURE
+*****************************
+2 bytes - uint16 type of the request this answers
+1 byte - field that was changed, 0 unless this answers a misc update
+2 bytes + 36 bytes - room id
+1 byte - result (0 - ok, anything else is an error)
+2 bytes + <n> bytes - human readable result
+*/
+func sendRoomUpdateResult(connection *Connection, request PacketType, field uint8, roomID string, result uint8) {
+	pkt := NewPacket(MsgRoomUpdateResult)
+	pkt.WriteUint16(uint16(request))
+	pkt.WriteUint8(field)
+	pkt.WriteString(roomID)
+	pkt.WriteUint8(result)
+	pkt.WriteString(roomUpdateResultText[result])
+	sendMessageToConnection(connection, *pkt)
+}
+
+// broadcastRoomInfo sends the room again to everyone viewing it after its details changed, v3 clients only get the header
+func (room *Room) broadcastRoomInfo() {
+	for _, connection := range ServerInstance.connectionsList {
+		if connection.telnet != nil || !connection.isViewing(room) || !room.fitsConnection(connection) {
+			continue
+		}
+		msg := NewPacket(MsgRoomCountResponse)
+		connection.writeRoom(msg, room)
+		sendMessageToConnection(connection, *msg)
+	}
+}
+
+// builderRoom finds the room a builder asked to change. Anyone else gets no room and ROOM_UPDATE_ERROR_NOT_ALLOWED,
+// handlers still read the rest of their request so a denied request is answered like any other that changes nothing.
+func builderRoom(connection *Connection, roomID string) (*Room, uint8) {
+	if connection.player == nil || connection.player.role < ROLE_BUILDER {
+		return nil, ROOM_UPDATE_ERROR_NOT_ALLOWED
+	}
+	if room := ServerInstance.FindRoom(roomID); room != nil {
+		return room, ROOM_UPDATE_OK
+	}
+	return nil, ROOM_UPDATE_ERROR_UNKNOWN_ROOM
+}
+
+// validStandingTile checks that a player can be placed on the position, used for the entry and exit
+func (room *Room) validStandingTile(pos Vector2) uint8 {
+	if !room.inBounds(pos) {
+		return ROOM_UPDATE_ERROR_OUT_OF_BOUNDS
+	}
+	if !room.Tiles[pos.X][pos.Y].IsPassable {
+		return ROOM_UPDATE_ERROR_NOT_PASSABLE
+	}
+	return ROOM_UPDATE_OK
+}
+
+type RoomUpdateNameHandler struct{}
+
+/*
+*****************************
+ROOM UPDATE NAME STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+2 bytes + <n> bytes - room name, at most 64 characters
+2 bytes + <n> bytes - room description, at most 1024 characters
+*/
+func (h RoomUpdateNameHandler) handle(packet *Packet) {
+	roomID := packet.ReadUUID()
+	name := strings.TrimSpace(string(packet.ReadBytes(uint32(packet.ReadUint16()))))
+	description := strings.TrimSpace(string(packet.ReadBytes(uint32(packet.ReadUint16()))))
+
+	connection := packet.Connection
+	result := ROOM_UPDATE_ERROR_NOT_ALLOWED
+	if connection.player != nil && connection.player.role >= ROLE_BUILDER {
+		result = renameRoom(roomID, name, description)
+	}
+	sendRoomUpdateResult(connection, MsgRoomUpdateName, ROOM_FIELD_NONE, roomID, result)
+}
+
+func renameRoom(roomID, name, description string) uint8 {
+	room := ServerInstance.FindRoom(roomID)
+	if room == nil {
+		return ROOM_UPDATE_ERROR_UNKNOWN_ROOM
+	}
+	if !validRoomName(name) {
+		return ROOM_UPDATE_ERROR_INVALID_NAME
+	}
+	if !utf8.ValidString(description) || utf8.RuneCountInString(description) > maxRoomDescriptionLength {
+		return ROOM_UPDATE_ERROR_INVALID_DESCRIPTION
+	}
+
+	room.Name = name
+	room.Description = description
+	room.broadcastRoomInfo()
+	room.save()
+	return ROOM_UPDATE_OK
+}
+
+func validRoomName(name string) bool {
+	return name != "" && utf8.ValidString(name) && utf8.RuneCountInString(name) <= maxRoomNameLength
+}
+
+type RoomUpdateStatusHandler struct{}
+
+/*
+*****************************
+ROOM UPDATE STATUS STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+1 byte - bool room is active
+*/
+func (h RoomUpdateStatusHandler) handle(packet *Packet) {
+	roomID := packet.ReadUUID()
+	active := packet.ReadBoolean()
+
+	room, result := builderRoom(packet.Connection, roomID)
+	if room != nil {
+		// The status is not part of the room data clients have, so there is nothing to broadcast
+		room.isActive = active
+		room.save()
+	}
+	sendRoomUpdateResult(packet.Connection, MsgRoomUpdateStatus, ROOM_FIELD_NONE, roomID, result)
+}
+
+type RoomMiscUpdateHandler struct{}
+
+/*
+*****************************
+ROOM MISC UPDATE STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+1 byte - field (1 - entry, 2 - exit, 3 - exit destinations, 4 - starting room)
+... entry and exit:
+... 1 byte - position X, uint16 from protocol version 3
+... 1 byte - position Y, uint16 from protocol version 3
+... exit destinations:
+... 2 bytes - uint16 number of destinations
+... ... 2 bytes + 36 bytes - destination room id
+... starting room:
+... 1 byte - bool room is the starting room, there is only ever one
+*/
+func (h RoomMiscUpdateHandler) handle(packet *Packet) {
+	roomID := packet.ReadUUID()
+	field := packet.ReadUint8()
+	room, result := builderRoom(packet.Connection, roomID)
+
+	switch field {
+	case ROOM_FIELD_ENTRY, ROOM_FIELD_EXIT:
+		wide := packet.Connection.wideCoordinates()
+		pos := Vector2{packet.ReadCoordinate(wide), packet.ReadCoordinate(wide)}
+		if room != nil {
+			result = room.moveEndpoint(field, pos)
+		}
+	case ROOM_FIELD_DESTINATIONS:
+		count := packet.ReadUint16AsInt()
+		ids := make([]string, 0, count)
+		for i := 0; i < count; i++ {
+			ids = append(ids, packet.ReadUUID())
+		}
+		if room != nil {
+			result = room.setDestinations(ids)
+		}
+	case ROOM_FIELD_STARTING:
+		starting := packet.ReadBoolean()
+		if room != nil {
+			result = room.setStarting(starting)
+		}
+	default:
+		result = ROOM_UPDATE_ERROR_UNKNOWN_FIELD
+	}
+
+	if result == ROOM_UPDATE_OK {
+		room.save()
+		// Only the entry and the exit are part of the room data clients have
+		if field == ROOM_FIELD_ENTRY || field == ROOM_FIELD_EXIT {
+			room.broadcastRoomInfo()
+		}
+	}
+	sendRoomUpdateResult(packet.Connection, MsgRoomMiscUpdate, field, roomID, result)
+}
+
+// moveEndpoint moves the entry or the exit of the room, both have to be tiles a player can stand on and
+// the exit has to stay reachable from the entry
+func (room *Room) moveEndpoint(field uint8, pos Vector2) uint8 {
+	if result := room.validStandingTile(pos); result != ROOM_UPDATE_OK {
+		return result
+	}
+	other := room.Exit.LocationInRoom
+	if field == ROOM_FIELD_EXIT {
+		other = room.Entry.LocationInRoom
+	}
+	if !room.reachable(room, pos, other) && room.reachable(room, room.Entry.LocationInRoom, room.Exit.LocationInRoom) {
+		return ROOM_UPDATE_ERROR_UNREACHABLE
+	}
+	if field == ROOM_FIELD_ENTRY {
+		room.Entry.LocationInRoom = pos
+	} else {
+		room.Exit.LocationInRoom = pos
+	}
+	return ROOM_UPDATE_OK
+}
+
+// setDestinations replaces where the exit of the room leads, every destination has to be an existing room
+func (room *Room) setDestinations(ids []string) uint8 {
+	destinations := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		destination := ServerInstance.FindRoom(id)
+		if destination == nil {
+			return ROOM_UPDATE_ERROR_UNKNOWN_DESTINATION
+		}
+		if !seen[destination.ID] {
+			seen[destination.ID] = true
+			destinations = append(destinations, destination.ID)
+		}
+	}
+	room.Exit.Destinations = destinations
+	return ROOM_UPDATE_OK
+}
+
+// setStarting makes the room the one new players join in, or stops it from being one
+func (room *Room) setStarting(starting bool) uint8 {
+	if starting {
+		for _, other := range ServerInstance.roomList {
+			if other != room && other.IsStartingRoom {
+				other.IsStartingRoom = false
+				other.save()
+			}
+		}
+	}
+	room.IsStartingRoom = starting
+	return ROOM_UPDATE_OK
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"time"
+
+	"github.com/Entrio/aeonofstrife/pathfinding"
+	"github.com/google/uuid"
+)
+
+const (
+	playerMoveIntervalMs = 150
+)
+
+// Size returns the room dimensions, it is used by the pathfinding package
+func (room *Room) Size() (int, int) {
+	return room.Width, room.Height
+}
+
+// IsPassable reports whether the tile at the given coordinates can be walked on
+func (room *Room) IsPassable(x, y int) bool {
+	return room.inBounds(Vector2{x, y}) && room.Tiles[x][y].IsPassable
+}
+
+// MovementCost returns how slow the tile at the given coordinates is to cross, it is used by the pathfinding package
+func (room *Room) MovementCost(x, y int) int {
+	return tileTypeOf(room.Tiles[x][y].Type).MovementCost
+}
+
+// findPath returns the tiles to walk through to get from one position to another, avoiding other entities
+func (room *Room) findPath(from, to Vector2) ([]Vector2, bool) {
+	if room.pathCache == nil {
+		room.pathCache = pathfinding.NewCache()
+	}
+
+	opts := pathfinding.Options{
+		Diagonal: ServerInstance.config.DiagonalMovement,
+		Blocked: func(x, y int) bool {
+			return room.blockingEntityAt(Vector2{x, y}) != nil
+		},
+	}
+
+	points, ok := room.pathCache.FindPath(room, room.revision, toPoint(from), toPoint(to), opts)
+	if !ok {
+		return nil, false
+	}
+
+	path := make([]Vector2, len(points))
+	for i, p := range points {
+		path[i] = Vector2{p.X, p.Y}
+	}
+	return path, true
+}
+
+// nextStepTowards returns the first step of the path between two positions
+func (room *Room) nextStepTowards(from, to Vector2) (Vector2, bool) {
+	path, ok := room.findPath(from, to)
+	if !ok || len(path) == 0 || !room.isWalkable(path[0]) {
+		return from, false
+	}
+	return path[0], true
+}
+
+// Destinations lists the rooms reachable from the exit of the given room, it is used for cross room routing
+func (server *Server) Destinations(roomID string) []string {
+	room := server.FindRoom(roomID)
+	if room == nil {
+		return nil
+	}
+
+	list := make([]string, 0, len(room.Exit.Destinations))
+	for _, d := range room.Exit.Destinations {
+		if server.FindRoom(d.String()) != nil {
+			list = append(list, d.String())
+		}
+	}
+	return list
+}
+
+// setDestination plans a route for the player to a position in any room connected to the current one
+func (p *Player) setDestination(roomID uuid.UUID, target Vector2) bool {
+	route, ok := pathfinding.FindRoute(ServerInstance, p.currentRoom.String(), roomID.String())
+	if !ok {
+		return false
+	}
+
+	p.route = route[1:]
+	p.target = target
+	return p.planPath()
+}
+
+// planPath computes the path inside the current room, either to the final target or to the room exit
+func (p *Player) planPath() bool {
+	room := ServerInstance.FindRoom(p.currentRoom.String())
+	if room == nil {
+		return false
+	}
+
+	goal := p.target
+	if len(p.route) > 0 {
+		goal = room.Exit.LocationInRoom
+	}
+
+	path, ok := room.findPath(p.position, goal)
+	if !ok {
+		p.clearPath()
+		return false
+	}
+	p.path = path
+	return true
+}
+
+func (p *Player) clearPath() {
+	p.path = nil
+	p.route = nil
+}
+
+// followPath moves the player a single tile along their planned path
+func (p *Player) followPath(room *Room, now time.Time) {
+	if len(p.path) == 0 || now.Before(p.nextMove) {
+		return
+	}
+	p.nextMove = now.Add(time.Millisecond * time.Duration(p.moveInterval()))
+
+	if !room.moveEntity(p, p.path[0]) {
+		// Something is standing in the way, try to walk around it
+		if !p.planPath() {
+			return
+		}
+		if !room.moveEntity(p, p.path[0]) {
+			return
+		}
+	}
+	p.path = p.path[1:]
+
+	if len(p.path) == 0 && len(p.route) > 0 && p.position == room.Exit.LocationInRoom {
+		next := ServerInstance.FindRoom(p.route[0])
+		p.route = p.route[1:]
+		if next == nil || !next.fitsConnection(p.connection) {
+			p.clearPath()
+			return
+		}
+		transferPlayer(p, room, next)
+		p.planPath()
+	}
+}
+
+// transferPlayer moves a player through the exit of one room into the entry of another
+func transferPlayer(p *Player, from, to *Room) {
+	from.removeEntity(p, DESPAWN_REASON_LEFT)
+	placePlayerInRoom(p, to, to.Entry.LocationInRoom)
+}
+
+func toPoint(v Vector2) pathfinding.Point {
+	return pathfinding.Point{X: v.X, Y: v.Y}
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// roomRecord is a room as it is saved in the data directory, the tiles use the same runs as ROOM DATA V2 one strip of
+// rows after the other
+type roomRecord struct {
+	*Room
+	Active   bool       `json:"active"`
+	Tiles    string     `json:"tiles"`
+	Revision uint64     `json:"revision"`
+	History  []roomEdit `json:"history"`
+	Undo     []roomEdit `json:"undo"`
+	Redo     []roomEdit `json:"redo"`
+}
+
+// tileStripHeight is how many rows of the room are saved as one set of runs, a set can't count more than 65535 runs
+func tileStripHeight(width int) int {
+	if height := 0xFFFF / width; height > 0 {
+		return height
+	}
+	return 1
+}
+
+func roomsPath() string {
+	return path.Join(ServerInstance.dataPath, "rooms")
+}
+
+func roomPath(id string) string {
+	return path.Join(roomsPath(), id+".json")
+}
+
+// save writes the room to the data directory, it is called after every change so a restart loses nothing
+func (room *Room) save() {
+	if ServerInstance.dataPath == "" {
+		return
+	}
+	if _, err := os.Stat(roomsPath()); os.IsNotExist(err) {
+		if err := os.Mkdir(roomsPath(), 0777); err != nil {
+			log.Warn().Err(err).Msg("Failed to create rooms path")
+			return
+		}
+	}
+
+	tiles := NewPacket(MsgNullIota)
+	strip := tileStripHeight(room.Width)
+	for y := 0; y < room.Height; y += strip {
+		tiles.writeTileRuns(room, 0, y, room.Width, minInt(strip, room.Height-y), nil)
+	}
+	record := roomRecord{
+		Room:     room,
+		Active:   room.isActive,
+		Tiles:    base64.StdEncoding.EncodeToString(tiles.buffer),
+		Revision: room.revision,
+		History:  room.history,
+		Undo:     room.undo,
+		Redo:     room.redo,
+	}
+
+	// Rooms are saved after every edit, indenting would only make that slower
+	jData, _ := json.Marshal(record)
+	if err := ioutil.WriteFile(roomPath(room.ID.String()), jData, 0666); err != nil {
+		log.Warn().Err(err).Str("room", room.ID.String()).Msg("Failed to write room to disk")
+	}
+}
+
+// deleteRoomFile removes a deleted room from the data directory
+func deleteRoomFile(id string) {
+	if err := os.Remove(roomPath(id)); err != nil && !os.IsNotExist(err) {
+		log.Warn().Err(err).Str("room", id).Msg("Failed to delete room from disk")
+	}
+}
+
+// loadRoomFiles reads every saved room, a room that can't be read is skipped so one bad file doesn't stop the server
+func loadRoomFiles(dataPath string) []*Room {
+	rooms := make([]*Room, 0)
+	files, err := ioutil.ReadDir(path.Join(dataPath, "rooms"))
+	if err != nil {
+		return rooms
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		room, err := loadRoomFile(path.Join(dataPath, "rooms", file.Name()))
+		if err != nil {
+			log.Warn().Err(err).Str("file", file.Name()).Msg("Failed to load room")
+			continue
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+func loadRoomFile(filePath string) (*Room, error) {
+	fData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	record := roomRecord{Room: &Room{}}
+	if err := json.Unmarshal(fData, &record); err != nil {
+		return nil, err
+	}
+	if record.Width <= 0 || record.Height <= 0 || record.Width > maxRoomSize || record.Height > maxRoomSize {
+		return nil, fmt.Errorf("room size %dx%d is out of range", record.Width, record.Height)
+	}
+	tiles, err := base64.StdEncoding.DecodeString(record.Tiles)
+	if err != nil {
+		return nil, err
+	}
+
+	room := NewRoom(record.Width, record.Height)
+	room.ID = record.ID
+	room.Name = record.Name
+	room.Description = record.Description
+	room.Entry = record.Entry
+	room.Exit = record.Exit
+	room.IsStartingRoom = record.IsStartingRoom
+	room.isActive = record.Active
+	room.revision = record.Revision
+	room.history = record.History
+	room.undo = record.Undo
+	room.redo = record.Redo
+	if record.Spawners != nil {
+		room.Spawners = record.Spawners
+	}
+	runs := NewUnknownPacket(tiles)
+	strip := tileStripHeight(room.Width)
+	for y := 0; y < room.Height; y += strip {
+		if err := runs.readTileRuns(room, 0, y, room.Width, minInt(strip, room.Height-y)); err != nil {
+			return nil, err
+		}
+	}
+	return room, nil
+}
@@ -0,0 +1,32 @@
+package game
+
+import "testing"
+
+func TestLargeRoomSurvivesSaving(t *testing.T) {
+	ServerInstance = &Server{config: &serverConfig{}, roomList: map[string]*Room{}, dataPath: t.TempDir()}
+
+	// A checkerboard is the worst case for runs, every tile is a run of its own
+	room := NewRoom(300, 300)
+	for x := 0; x < room.Width; x++ {
+		for y := 0; y < room.Height; y++ {
+			tileType := TILE_TYPE_DIRT
+			if (x+y)%2 == 0 {
+				tileType = TILE_TYPE_WALL
+			}
+			room.Tiles[x][y] = Tile{Type: tileType, IsPassable: tileType == TILE_TYPE_DIRT, Position: Vector2{x, y}}
+		}
+	}
+	room.save()
+
+	loaded, err := loadRoomFile(roomPath(room.ID.String()))
+	if err != nil {
+		t.Fatalf("Expected the saved room to load but got %v", err)
+	}
+	for x := 0; x < room.Width; x++ {
+		for y := 0; y < room.Height; y++ {
+			if loaded.Tiles[x][y] != room.Tiles[x][y] {
+				t.Fatalf("Expected tile %d, %d to be %+v after loading but it was %+v", x, y, room.Tiles[x][y], loaded.Tiles[x][y])
+			}
+		}
+	}
+}
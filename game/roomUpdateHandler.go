@@ -1,6 +1,14 @@
 package game
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ROOM_UPDATE_TILES = uint8(iota)
+	ROOM_UPDATE_TILES_FROM_REVISION
+)
 
 type RoomUpdateHandler struct{}
 
@@ -8,23 +16,44 @@ type RoomUpdateHandler struct{}
 *****************************
 ROOM UPDATE PAYLOAD STRUCTURE
 *****************************
-1 byte - update type (0 - tiles)
+1 byte - update type (0 - tiles, 1 - tiles based on a revision)
 16 bytes - string - room ID
+... tiles based on a revision:
+... 8 bytes - uint64 room revision the edit was made against
 2 bytes - uint16 number fo tiles that we will be sending
 ... 1 byte - tile type uint8 (max 255)
 ... 1 byte - is passable byte
-... 1 byte - positionX uint8 (max 255)
-... 1 byte - positionY uint8 (max 255)
+... 1 byte - positionX uint8, uint16 from protocol version 3
+... 1 byte - positionY uint8, uint16 from protocol version 3
+
+Response:
+2 bytes + 36 bytes - room id
+8 bytes - uint64 room revision after the change
+2 bytes - uint16 number of tiles applied
+
+Everyone else viewing the room gets the tiles as a delta, the editor keeps getting deltas for the room.
+Every batch is recorded in the room history with the editor as its author and can be undone.
+An edit based on a revision that touches tiles changed since is answered with ROOM EDIT CONFLICT instead,
+an edit touching tiles someone else holds a lock on is answered with a room update result.
+Edits are validated first, a tile outside of the room or of an unknown type, an impassable entry or exit, or walls
+cutting the exit off from the entry reject the whole edit with a room update result followed by ROOM EDIT REJECTED.
+Only builders can edit tiles, an edit from anyone else is answered with a room update result.
 */
 
 func (r RoomUpdateHandler) handle(packet *Packet) {
-	updateType := packet.ReadBytes(1)
+	updateType := packet.ReadUint8()
 	roomID := packet.ReadUUID()
+	var base uint64
+	if updateType == ROOM_UPDATE_TILES_FROM_REVISION {
+		base = packet.ReadUint64()
+	}
 	tileCount := packet.ReadUint16AsInt()
+	wide := packet.Connection.wideCoordinates()
 
-	room := ServerInstance.FindRoom(roomID)
+	room, result := builderRoom(packet.Connection, roomID)
 	if room == nil {
-		panic(fmt.Sprintf("Failed to find room with UUID %s", roomID))
+		sendRoomUpdateResult(packet.Connection, MsgUpdateRoomPayload, ROOM_FIELD_NONE, roomID, result)
+		return
 	}
 
 	fmt.Println(
@@ -34,23 +63,22 @@ func (r RoomUpdateHandler) handle(packet *Packet) {
 		),
 	)
 
+	tiles := make([]Tile, 0, tileCount)
 	for i := 0; i < tileCount; i++ {
 		// pew pew lasers
 		_tileType := uint8(packet.ReadByte())
 		_isPassable := packet.ReadBoolean()
-		_posX := packet.ReadUint8()
-		_posY := packet.ReadUint8()
+		_posX := packet.ReadCoordinate(wide)
+		_posY := packet.ReadCoordinate(wide)
 
-		tile := Tile{
+		tiles = append(tiles, Tile{
 			Type:       _tileType,
 			IsPassable: _isPassable,
 			Position: Vector2{
-				X: int(_posX),
-				Y: int(_posY),
+				X: _posX,
+				Y: _posY,
 			},
-		}
-
-		room.UpdateTile(_posX, _posY, tile)
+		})
 
 		fmt.Println(
 			fmt.Sprintf(
@@ -60,4 +88,31 @@ func (r RoomUpdateHandler) handle(packet *Packet) {
 		)
 	}
 
+	if result, pos := room.validateTiles(tiles); result != ROOM_UPDATE_OK {
+		sendRoomUpdateResult(packet.Connection, MsgUpdateRoomPayload, ROOM_FIELD_NONE, roomID, result)
+		sendEditRejected(packet.Connection, room, result, pos)
+		return
+	}
+
+	now := time.Now()
+	if updateType == ROOM_UPDATE_TILES_FROM_REVISION && room.isStale(tiles, base) {
+		sendEditConflict(packet.Connection, room, tiles)
+		return
+	}
+	for _, tile := range tiles {
+		if room.lockedFor(packet.Connection, tile.Position, now) != nil {
+			sendRoomUpdateResult(packet.Connection, MsgUpdateRoomPayload, ROOM_FIELD_NONE, roomID, ROOM_UPDATE_ERROR_LOCKED)
+			return
+		}
+	}
+
+	revision := room.editTiles(tiles, packet.Connection.editorName(), packet.Connection)
+	room.renewLocks(packet.Connection, now)
+	packet.Connection.watch(room)
+
+	ack := NewPacket(MsgUpdateRoomPayloadAck)
+	ack.WriteString(room.ID.String())
+	ack.WriteUint64(revision)
+	ack.WriteUint16(uint16(len(tiles)))
+	sendMessageToConnection(packet.Connection, *ack)
 }
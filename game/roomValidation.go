@@ -0,0 +1,85 @@
+package game
+
+import "github.com/Entrio/aeonofstrife/pathfinding"
+
+// tileOverlay is the room as it would look with an edit applied, edits are checked against it before they happen
+type tileOverlay struct {
+	room  *Room
+	tiles map[Vector2]Tile
+}
+
+func (o tileOverlay) Size() (int, int) {
+	return o.room.Size()
+}
+
+func (o tileOverlay) IsPassable(x, y int) bool {
+	if tile, found := o.tiles[Vector2{x, y}]; found {
+		return tile.IsPassable
+	}
+	return o.room.IsPassable(x, y)
+}
+
+// validTileType reports whether the tile type is in the tile data file
+func validTileType(tileType uint8) bool {
+	_, found := ServerInstance.tileTypes[tileType]
+	return found
+}
+
+// validateTiles checks an edit before it is applied and returns what is wrong with it along with the offending position.
+// Every tile has to be inside of the room and of a known type, the entry and exit have to stay passable and the exit
+// has to stay reachable from the entry. A room that was already cut in two is not held to the last rule.
+func (room *Room) validateTiles(tiles []Tile) (uint8, Vector2) {
+	overlay := tileOverlay{room: room, tiles: make(map[Vector2]Tile, len(tiles))}
+	blocking := false
+	for _, tile := range tiles {
+		if !room.inBounds(tile.Position) {
+			return ROOM_UPDATE_ERROR_OUT_OF_BOUNDS, tile.Position
+		}
+		if !validTileType(tile.Type) {
+			return ROOM_UPDATE_ERROR_UNKNOWN_TILE_TYPE, tile.Position
+		}
+		if !tile.IsPassable && room.Tiles[tile.Position.X][tile.Position.Y].IsPassable {
+			blocking = true
+		}
+		overlay.tiles[tile.Position] = tile
+	}
+
+	for _, pos := range []Vector2{room.Entry.LocationInRoom, room.Exit.LocationInRoom} {
+		if tile, found := overlay.tiles[pos]; found && !tile.IsPassable {
+			return ROOM_UPDATE_ERROR_NOT_PASSABLE, pos
+		}
+	}
+
+	// Only walls going up can cut the exit off
+	if blocking && !room.reachable(overlay, room.Entry.LocationInRoom, room.Exit.LocationInRoom) &&
+		room.reachable(room, room.Entry.LocationInRoom, room.Exit.LocationInRoom) {
+		return ROOM_UPDATE_ERROR_UNREACHABLE, room.Exit.LocationInRoom
+	}
+	return ROOM_UPDATE_OK, Vector2{}
+}
+
+// reachable flood fills the grid to find out whether players can walk from one position to the other
+func (room *Room) reachable(grid pathfinding.Grid, from, to Vector2) bool {
+	return pathfinding.Reachable(grid, toPoint(from), toPoint(to), pathfinding.Options{Diagonal: ServerInstance.config.DiagonalMovement})
+}
+
+/*
+*****************************
+ROOM EDIT REJECTED STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+1 byte - result, the same as in the room update result
+1 byte - position X of the tile that was rejected, uint16 from protocol version 3
+1 byte - position Y of the tile that was rejected, uint16 from protocol version 3
+
+Follows the room update result of a tile edit that failed validation so the editor can point at the problem.
+*/
+func sendEditRejected(connection *Connection, room *Room, result uint8, pos Vector2) {
+	wide := connection.wideCoordinates()
+	pkt := NewPacket(MsgRoomEditRejected)
+	pkt.WriteString(room.ID.String())
+	pkt.WriteUint8(result)
+	pkt.WriteCoordinate(pos.X, wide)
+	pkt.WriteCoordinate(pos.Y, wide)
+	sendMessageToConnection(connection, *pkt)
+}
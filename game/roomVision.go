@@ -0,0 +1,109 @@
+package game
+
+import (
+	"github.com/Entrio/aeonofstrife/fov"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultViewRadius = 8
+)
+
+// IsOpaque reports whether the tile blocks vision, it is used by the fov package
+func (room *Room) IsOpaque(x, y int) bool {
+	if !room.inBounds(Vector2{x, y}) {
+		return true
+	}
+	return tileTypeOf(room.Tiles[x][y].Type).Opaque
+}
+
+// hasLineOfSight checks whether nothing opaque stands between two positions
+func (room *Room) hasLineOfSight(from, to Vector2) bool {
+	return fov.LineOfSight(room, fov.Point{X: from.X, Y: from.Y}, fov.Point{X: to.X, Y: to.Y})
+}
+
+// fieldOfView computes every tile that can be seen from the given position
+func (room *Room) fieldOfView(from Vector2) *fov.Visibility {
+	return fov.Compute(room, fov.Point{X: from.X, Y: from.Y}, viewRadius())
+}
+
+func viewRadius() int {
+	if ServerInstance.config.ViewRadius > 0 {
+		return ServerInstance.config.ViewRadius
+	}
+	return defaultViewRadius
+}
+
+// canSee reports whether the entity is within view and line of sight of the player
+func (p *Player) canSee(room *Room, e entity) bool {
+	return distance(p.position, e.getPosition()) <= viewRadius() && room.hasLineOfSight(p.position, e.getPosition())
+}
+
+// hasExplored reports whether the player has seen the tile before. Without fog of war everything is explored.
+func (p *Player) hasExplored(room *Room, x, y int) bool {
+	if !ServerInstance.config.FogOfWar {
+		return true
+	}
+	explored, found := p.explored[room.ID]
+	if !found || x < 0 || y < 0 || x >= room.Width || y >= room.Height {
+		return false
+	}
+	return explored[x*room.Height+y]
+}
+
+/*
+updateView recomputes what the player can see and sends it along with every newly explored tile.
+This does nothing unless fog of war is enabled.
+
+*****************************
+FIELD OF VIEW STRUCTURE
+*****************************
+2 bytes + 36 bytes - room id
+2 bytes - uint16 number of visible tiles
+... 1 byte - positionX uint8, uint16 from protocol version 3
+... 1 byte - positionY uint8, uint16 from protocol version 3
+2 bytes - uint16 number of newly explored tiles
+... 1 byte - tile type uint8 (max 255)
+... 1 byte - is passable byte
+... 1 byte - positionX uint8, uint16 from protocol version 3
+... 1 byte - positionY uint8, uint16 from protocol version 3
+*/
+func (p *Player) updateView(room *Room) {
+	if !ServerInstance.config.FogOfWar || p.connection == nil {
+		return
+	}
+
+	if p.explored == nil {
+		p.explored = make(map[uuid.UUID][]bool)
+	}
+	explored, found := p.explored[room.ID]
+	if !found || len(explored) != room.Width*room.Height {
+		explored = make([]bool, room.Width*room.Height)
+		p.explored[room.ID] = explored
+	}
+
+	wide := p.connection.wideCoordinates()
+	visible := room.fieldOfView(p.position).Points()
+	revealed := make([]fov.Point, 0)
+
+	pkt := NewPacket(MsgPlayerFieldOfView)
+	pkt.WriteString(room.ID.String())
+	pkt.WriteUint16(uint16(len(visible)))
+	for _, v := range visible {
+		pkt.WriteCoordinate(v.X, wide).WriteCoordinate(v.Y, wide)
+		if !explored[v.X*room.Height+v.Y] {
+			explored[v.X*room.Height+v.Y] = true
+			revealed = append(revealed, v)
+		}
+	}
+
+	pkt.WriteUint16(uint16(len(revealed)))
+	for _, v := range revealed {
+		tile := room.Tiles[v.X][v.Y]
+		pkt.WriteUint8(tile.Type).
+			WriteBool(tile.IsPassable).
+			WriteCoordinate(v.X, wide).
+			WriteCoordinate(v.Y, wide)
+	}
+	sendMessageToConnection(p.connection, *pkt)
+}
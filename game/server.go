@@ -1,16 +1,17 @@
 package game
 
 import (
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"os"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/Entrio/subenv"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
@@ -24,6 +25,15 @@ type (
 		ticker          *time.Ticker
 		config          *serverConfig
 		packetHandler   map[PacketType]PacketHandler
+		npcTemplates    map[string]*npcTemplate
+		itemTemplates   map[string]*itemTemplate
+		tileTypes       map[uint8]*tileDefinition
+		prefabs         map[string]*prefab
+		progression     *progressionTable
+		dataPath        string
+		configPath      string
+		rng             *rand.Rand
+		mu              sync.Mutex
 	}
 	gameLoop struct {
 		ticker *time.Ticker
@@ -38,6 +48,37 @@ func GetServer() (*Server, error) {
 
 		handlers[MsgUpdateRoomPayload] = RoomUpdateHandler{}
 		handlers[MsgRoomCountRequest] = RoomCountHandler{}
+		handlers[MsgPlayerJoinRequest] = PlayerJoinHandler{}
+		handlers[MsgPlayerMoveRequest] = PlayerMoveHandler{}
+		handlers[MsgPlayerMoveToRequest] = PlayerMoveToHandler{}
+		handlers[MsgAttackRequest] = AttackHandler{}
+		handlers[MsgPickUpRequest] = PickUpHandler{}
+		handlers[MsgDropRequest] = DropHandler{}
+		handlers[MsgUseItemRequest] = UseItemHandler{}
+		handlers[MsgEquipRequest] = EquipHandler{}
+		handlers[MsgUnequipRequest] = UnequipHandler{}
+		handlers[MsgChatRequest] = ChatHandler{}
+		handlers[MsgChatIgnoreRequest] = ChatIgnoreHandler{}
+		handlers[MsgChatMuteRequest] = ChatChannelMuteHandler{}
+		handlers[MsgPartyInviteRequest] = PartyInviteHandler{}
+		handlers[MsgPartyAcceptRequest] = PartyAcceptHandler{}
+		handlers[MsgPartyLeaveRequest] = PartyLeaveHandler{}
+		handlers[MsgCommandRequest] = CommandHandler{}
+		handlers[MsgCompressionRequest] = CompressionHandler{}
+		handlers[MsgVersionRequest] = ProtocolVersionHandler{}
+		handlers[MsgRoomResyncRequest] = RoomResyncHandler{}
+		handlers[MsgRoomUpdateName] = RoomUpdateNameHandler{}
+		handlers[MsgRoomUpdateStatus] = RoomUpdateStatusHandler{}
+		handlers[MsgRoomMiscUpdate] = RoomMiscUpdateHandler{}
+		handlers[MsgRoomCreateRequest] = RoomCreateHandler{}
+		handlers[MsgRoomCloneRequest] = RoomCloneHandler{}
+		handlers[MsgRoomDeleteRequest] = RoomDeleteHandler{}
+		handlers[MsgRoomUndoRequest] = RoomUndoHandler{}
+		handlers[MsgRoomRedoRequest] = RoomRedoHandler{}
+		handlers[MsgRoomLockRequest] = RoomLockHandler{}
+		handlers[MsgPrefabSaveRequest] = PrefabSaveHandler{}
+		handlers[MsgPrefabStampRequest] = PrefabStampHandler{}
+		handlers[MsgPrefabListRequest] = PrefabListHandler{}
 
 		log.Debug().Int("count", len(handlers)).Msg("Total handlers")
 
@@ -60,13 +101,54 @@ func GetServer() (*Server, error) {
 		return nil, err
 	}
 	ServerInstance.config = config
+	if len(config.Admins) > 0 && config.AdminToken == "" {
+		log.Warn().Msg("Admins are configured without an admin_token, they will not be able to join")
+	}
 
-	/*
-		err = loadServerRooms(dirs[1])
-		if err != nil {
-			return nil, err
-		}
-	*/
+	seed := config.CombatSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	ServerInstance.rng = rand.New(rand.NewSource(seed))
+	log.Debug().Int64("seed", seed).Msg("Seeded combat random number generator")
+
+	ServerInstance.dataPath = dirs[1]
+	ServerInstance.configPath = dirs[0]
+
+	progression, err := loadProgressionTable(dirs[0])
+	if err != nil {
+		return nil, err
+	}
+	ServerInstance.progression = progression
+
+	items, err := loadItemTemplates(dirs[1])
+	if err != nil {
+		return nil, err
+	}
+	ServerInstance.itemTemplates = items
+
+	tileTypes, err := loadTileTypes(dirs[1])
+	if err != nil {
+		return nil, err
+	}
+	ServerInstance.tileTypes = tileTypes
+
+	prefabs, err := loadPrefabs(dirs[1])
+	if err != nil {
+		return nil, err
+	}
+	ServerInstance.prefabs = prefabs
+
+	templates, err := loadNPCTemplates(dirs[1])
+	if err != nil {
+		return nil, err
+	}
+	ServerInstance.npcTemplates = templates
+
+	err = loadServerRooms(dirs[1])
+	if err != nil {
+		return nil, err
+	}
 	return ServerInstance, nil
 }
 
@@ -79,8 +161,8 @@ func (server *Server) Start() {
 	}
 	go func() {
 		log.Info().Msg("Starting game loop")
-		for range server.gameLoop.ticker.C {
-			// Do game loop login and handlers here
+		for now := range server.gameLoop.ticker.C {
+			server.tick(now)
 		}
 	}()
 
@@ -89,20 +171,36 @@ func (server *Server) Start() {
 		for range server.ticker.C {
 			pkt := NewPacket(MsgPingRequest)
 
+			server.mu.Lock()
 			for _, c := range server.connectionsList {
 				// game loop
 				if server.config.PingConnections {
 					sendMessageToConnection(c, *pkt)
 				}
 			}
+			server.mu.Unlock()
 
 		}
 	}()
 }
 
+// tick advances the world by a single game loop step
+func (server *Server) tick(now time.Time) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for _, room := range server.roomList {
+		room.tick(now)
+	}
+}
+
 func (server *Server) GetPort() int {
 	return server.config.ServerPort
 }
+func (server *Server) GetTelnetPort() int {
+	return server.config.TelnetPort
+}
+
 func (server *Server) GetAddress() string {
 	return server.config.ServerAddress
 }
@@ -121,6 +219,20 @@ func (server *Server) FindRoom(uuid string) *Room {
 	return nil
 }
 
+// findStartingRoom returns the room new players are placed in, falling back to any room
+func (server *Server) findStartingRoom() *Room {
+	var fallback *Room
+	for _, room := range server.roomList {
+		if room.IsStartingRoom {
+			return room
+		}
+		if fallback == nil {
+			fallback = room
+		}
+	}
+	return fallback
+}
+
 // checkDirectories makes sure that all of the required directories exist
 func checkDirectories(cwd string) []string {
 	var err error
@@ -163,10 +275,13 @@ func checkServerConfig(configPath string) (*serverConfig, error) {
 
 		// Create default config file...
 		conf := serverConfig{
-			ServerName:      "Default MUD server",
-			ServerPort:      1337,
-			ServerAddress:   "127.0.0.1",
-			PingConnections: false,
+			ServerName:         "Default MUD server",
+			ServerPort:         1337,
+			ServerAddress:      "127.0.0.1",
+			PingConnections:    false,
+			ViewRadius:         defaultViewRadius,
+			InventorySlots:     defaultInventorySlots,
+			InventoryMaxWeight: defaultInventoryWeight,
 			RoomData: struct {
 				Config struct {
 					MinWidth  int `json:"min_width"`
@@ -175,6 +290,7 @@ func checkServerConfig(configPath string) (*serverConfig, error) {
 					MaxHeight int `json:"maxHeight"`
 				} `json:"config"`
 				MinRooms int `json:"min_rooms"`
+				Prefabs  int `json:"prefabs"`
 			}{
 				Config: struct {
 					MinWidth  int `json:"min_width"`
@@ -188,9 +304,25 @@ func checkServerConfig(configPath string) (*serverConfig, error) {
 					MaxHeight: 100,
 				},
 				MinRooms: 6,
+				Prefabs:  2,
 			},
 		}
 
+		conf.Chat.MaxMessageLength = defaultChatMaxLength
+		conf.Chat.RateLimitMessages = defaultChatRateMessages
+		conf.Chat.RateLimitSeconds = defaultChatRateSeconds
+		conf.Chat.SpamMuteSeconds = defaultChatSpamMuteSeconds
+		conf.Chat.Profanity = []string{}
+		conf.Compression.Enabled = true
+		conf.Compression.Threshold = defaultCompressionThreshold
+		conf.TLS.CertFile = defaultCertFile
+		conf.TLS.KeyFile = defaultKeyFile
+		conf.TLS.MinVersion = "1.2"
+		conf.TLS.SelfSigned = true
+		// Random UUIDs are good enough as a secret, builders and admins have to send theirs to get their role
+		conf.AdminToken = uuid.New().String()
+		conf.BuilderToken = uuid.New().String()
+
 		jData, _ := json.MarshalIndent(conf, "", " ")
 		err = ioutil.WriteFile(configFilePath, jData, 0666)
 		if err != nil {
@@ -215,95 +347,83 @@ func checkServerConfig(configPath string) (*serverConfig, error) {
 }
 
 func loadServerRooms(dataPath string) error {
-	roomFilePath := path.Join(dataPath, "rooms.blob")
-	_, err := os.Stat(roomFilePath)
+	for _, room := range loadRoomFiles(dataPath) {
+		ServerInstance.roomList[room.ID.String()] = room
+	}
 
-	if os.IsNotExist(err) {
+	if len(ServerInstance.roomList) == 0 {
+		generated := make([]*Room, 0, ServerInstance.config.RoomData.MinRooms)
 
 		for i := 0; i < ServerInstance.config.RoomData.MinRooms; i++ {
 			time.Sleep(time.Millisecond * 250)
 			rand.Seed(time.Now().UnixNano())
 			// Generate 1 room to start with
-			width := rand.Intn(ServerInstance.config.RoomData.Config.MaxWidth-ServerInstance.config.RoomData.Config.MinWidth) + ServerInstance.config.RoomData.Config.MinWidth
-			height := rand.Intn(ServerInstance.config.RoomData.Config.MaxHeight-ServerInstance.config.RoomData.Config.MinHeight) + ServerInstance.config.RoomData.Config.MinHeight
+			minWidth, maxWidth := roomWidthBounds()
+			minHeight, maxHeight := roomHeightBounds()
+			width := rand.Intn(maxWidth-minWidth+1) + minWidth
+			height := rand.Intn(maxHeight-minHeight+1) + minHeight
 			fmt.Println(fmt.Sprintf("Generating a new room, size (width x height): %d x %d", width, height))
 
-			newRoom := NewRoom(width, height)
-			for x := 0; x < width; x++ {
-				for y := 0; y < height; y++ {
-
-					tt := TILE_TYPE_AIR
-					pass := false
-
-					if x == 0 || y == 0 {
-						tt = TILE_TYPE_WALL
-						pass = false
-					}
-
-					if x+1 == width || y+1 == height {
-						tt = TILE_TYPE_WALL
-						pass = false
-					}
-
-					t := Tile{
-						Type:       tt,
-						IsPassable: pass,
-						Position:   Vector2{x, y},
-					}
-
-					newRoom.Tiles[x][y] = t
-				}
+			newRoom := generateRoom(width, height)
+			newRoom.IsStartingRoom = i == 0
+			if !newRoom.IsStartingRoom {
+				newRoom.Spawners = append(newRoom.Spawners, NewSpawner("rat", Vector2{width / 2, height / 2}, 3, 30))
 			}
+			if i%2 == 1 {
+				patrol := NewSpawner("goblin", Vector2{1, 1}, 1, 60)
+				patrol.Waypoints = []Vector2{{1, 1}, {width - 2, 1}, {width - 2, height - 2}, {1, height - 2}}
+				newRoom.Spawners = append(newRoom.Spawners, patrol)
+			}
+			newRoom.placePrefabs(ServerInstance.config.RoomData.Prefabs)
 			ServerInstance.roomList[newRoom.ID.String()] = newRoom
+			generated = append(generated, newRoom)
+		}
+
+		// Chain the generated rooms together so that every room can be reached
+		for i, room := range generated {
+			room.Exit.Destinations = []uuid.UUID{generated[(i+1)%len(generated)].ID}
+			room.save()
 		}
-		go saveServerRooms(dataPath)
 	}
 	return nil
 }
 
-/**
-Room structure:
-uint16 number of rooms
-.... cycle of rooms
-36 bytes - room uuid
-uint16 - number of bytes used for description (max 65535)
-.. <n> bytes - description
-byte - 1 byte used to indicate if room is active or not
-*/
-func saveServerRooms(dataPath string) error {
-	roomFilePath := path.Join(dataPath, "rooms.blob")
-	_, err := os.Stat(roomFilePath)
+// generateRoom builds an empty room, walls around a dirt floor with the exit in the far corner
+func generateRoom(width, height int) *Room {
+	newRoom := NewRoom(width, height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
 
-	if os.IsNotExist(err) {
-		// no room index
-		buffer := make([]byte, 0)
+			tt := TILE_TYPE_DIRT
 
-		tBuffer := make([]byte, 2)
-		binary.LittleEndian.PutUint16(tBuffer, uint16(len(ServerInstance.roomList)))
+			if x == 0 || y == 0 {
+				tt = TILE_TYPE_WALL
+			}
 
-		buffer = append(buffer, tBuffer...)
+			if x+1 == width || y+1 == height {
+				tt = TILE_TYPE_WALL
+			}
 
-		for _, v := range ServerInstance.roomList {
-			buffer = append(buffer, []byte(v.ID.String())...)
+			newRoom.Tiles[x][y] = newTile(tt, Vector2{x, y})
+		}
+	}
+	newRoom.Exit.LocationInRoom = Vector2{width - 2, height - 2}
+	return newRoom
+}
 
-			dBuffer := make([]byte, 2)
-			binary.LittleEndian.PutUint16(dBuffer, uint16(len(v.Description)))
-			buffer = append(buffer, dBuffer...)
-			buffer = append(buffer, []byte(v.Description)...)
+// roomWidthBounds returns the smallest and largest width a room may have. Positions go over the wire as uint16,
+// anything past that can't be addressed, and anything under minRoomSize has no floor.
+func roomWidthBounds() (int, int) {
+	return roomSizeBounds(ServerInstance.config.RoomData.Config.MinWidth, ServerInstance.config.RoomData.Config.MaxWidth)
+}
 
-			if v.isActive {
-				buffer = append(buffer, 0)
-			} else {
-				buffer = append(buffer, 1)
-			}
-		}
-		err := ioutil.WriteFile(roomFilePath, buffer, 0644)
-		check(err)
+func roomHeightBounds() (int, int) {
+	return roomSizeBounds(ServerInstance.config.RoomData.Config.MinHeight, ServerInstance.config.RoomData.Config.MaxHeight)
+}
 
-	} else {
-		// this file exists, need to update it
-	}
-	return nil
+func roomSizeBounds(min, max int) (int, int) {
+	min = clamp(min, minRoomSize, maxRoomSize)
+	return min, clamp(max, min, maxRoomSize)
 }
 
 func check(e error) {
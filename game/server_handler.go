@@ -2,19 +2,21 @@ package game
 
 import (
 	"fmt"
-	"net"
 	"time"
 )
 
 // AddConnection attempts to add a connection to the pool
-func (server *Server) AddConnection(conn net.Conn) *Connection {
+func (server *Server) AddConnection(transport Transport) *Connection {
 	newConnection := &Connection{
-		conn:          conn,
-		timeConnected: time.Now(),
-		player:        nil,
+		transport:       transport,
+		timeConnected:   time.Now(),
+		player:          nil,
+		protocolVersion: PROTOCOL_VERSION_1,
 	}
 
+	server.mu.Lock()
 	server.connectionsList = append(server.connectionsList, newConnection)
+	server.mu.Unlock()
 	go newConnection.listen()
 	welcomePacket := NewPacket(MsgWelcome)
 	welcomePacket.WriteString("Welcome to the super awesome server This is a server message!")
@@ -26,14 +28,23 @@ func (server *Server) AddConnection(conn net.Conn) *Connection {
 Handle player disconnects
 */
 func (server *Server) onClientConnectionClosed(connection *Connection, err error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if connection.player != nil {
+		removePlayerFromWorld(connection.player)
+		connection.player = nil
+	}
+
 	for i, conn := range server.connectionsList {
 		if conn == connection {
 			// bye bye, remove from the slice and reshuffle
 			server.connectionsList[i] = server.connectionsList[len(server.connectionsList)-1]
 			server.connectionsList[len(server.connectionsList)-1] = nil
 			server.connectionsList = server.connectionsList[:len(server.connectionsList)-1]
-			fmt.Println(fmt.Sprintf("Disconnect from from %s", connection.conn.RemoteAddr().String()))
+			fmt.Println(fmt.Sprintf("Disconnect from from %s", connection.transport.RemoteAddr().String()))
 			break
 		}
 	}
+	connection.leaveEditing()
 }
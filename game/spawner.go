@@ -0,0 +1,96 @@
+package game
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DESPAWN_REASON_REMOVED = uint8(iota)
+	DESPAWN_REASON_DIED
+	DESPAWN_REASON_LEFT
+)
+
+// Spawner keeps a room populated with npcs created from a single template
+type Spawner struct {
+	Template       string    `json:"template"`
+	Position       Vector2   `json:"position"`
+	Radius         int       `json:"radius"`
+	MaxPopulation  int       `json:"max_population"`
+	RespawnSeconds int       `json:"respawn_seconds"`
+	Waypoints      []Vector2 `json:"waypoints"`
+	alive          map[int64]*NPC
+	nextSpawn      time.Time
+}
+
+func NewSpawner(template string, position Vector2, maxPopulation, respawnSeconds int) *Spawner {
+	return &Spawner{
+		Template:       template,
+		Position:       position,
+		Radius:         3,
+		MaxPopulation:  maxPopulation,
+		RespawnSeconds: respawnSeconds,
+		alive:          make(map[int64]*NPC),
+	}
+}
+
+// tick spawns a single npc when the spawner is below its max population and the respawn timer has passed
+func (spawner *Spawner) tick(room *Room, now time.Time) {
+	if spawner.alive == nil {
+		spawner.alive = make(map[int64]*NPC)
+	}
+	if len(spawner.alive) >= spawner.MaxPopulation || now.Before(spawner.nextSpawn) {
+		return
+	}
+
+	template, found := ServerInstance.npcTemplates[spawner.Template]
+	if !found {
+		log.Warn().Str("template", spawner.Template).Str("room", room.ID.String()).Msg("Spawner references an unknown npc template")
+		spawner.nextSpawn = now.Add(spawner.respawnDelay())
+		return
+	}
+
+	position, ok := spawner.findSpawnPosition(room)
+	if !ok {
+		spawner.nextSpawn = now.Add(spawner.respawnDelay())
+		return
+	}
+
+	npc := newNPC(template, room, position)
+	npc.spawner = spawner
+	spawner.alive[npc.id] = npc
+	room.addEntity(npc)
+	spawner.nextSpawn = now.Add(spawner.respawnDelay())
+
+	log.Debug().Int64("id", npc.id).Str("template", template.ID).Str("room", room.ID.String()).Msg("Spawned npc")
+}
+
+// onDespawn releases the npc from the spawner and restarts the respawn timer
+func (spawner *Spawner) onDespawn(npc *NPC, now time.Time) {
+	delete(spawner.alive, npc.id)
+	spawner.nextSpawn = now.Add(spawner.respawnDelay())
+}
+
+func (spawner *Spawner) respawnDelay() time.Duration {
+	return time.Duration(spawner.RespawnSeconds) * time.Second
+}
+
+// findSpawnPosition picks a random free passable tile within the spawner radius
+func (spawner *Spawner) findSpawnPosition(room *Room) (Vector2, bool) {
+	candidates := make([]Vector2, 0)
+	for x := spawner.Position.X - spawner.Radius; x <= spawner.Position.X+spawner.Radius; x++ {
+		for y := spawner.Position.Y - spawner.Radius; y <= spawner.Position.Y+spawner.Radius; y++ {
+			pos := Vector2{x, y}
+			if room.isWalkable(pos) {
+				candidates = append(candidates, pos)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Vector2{}, false
+	}
+	return candidates[rand.Intn(len(candidates))], true
+}
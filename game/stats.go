@@ -0,0 +1,166 @@
+package game
+
+const (
+	defaultAttribute       = 10
+	baseMaxHP              = 50
+	hpPerVitality          = 5
+	minPlayerMoveInterval  = 80
+	moveIntervalPerAgility = 5
+)
+
+// attributes are the base values of a character that every other stat is derived from
+type attributes struct {
+	Strength   int `json:"strength"`
+	Agility    int `json:"agility"`
+	Vitality   int `json:"vitality"`
+	Perception int `json:"perception"`
+}
+
+func defaultAttributes() attributes {
+	return attributes{
+		Strength:   defaultAttribute,
+		Agility:    defaultAttribute,
+		Vitality:   defaultAttribute,
+		Perception: defaultAttribute,
+	}
+}
+
+func (a attributes) add(b attributes) attributes {
+	return attributes{
+		Strength:   a.Strength + b.Strength,
+		Agility:    a.Agility + b.Agility,
+		Vitality:   a.Vitality + b.Vitality,
+		Perception: a.Perception + b.Perception,
+	}
+}
+
+func (c combatStats) add(b combatStats) combatStats {
+	c.Attack += b.Attack
+	c.Defence += b.Defence
+	c.Accuracy += b.Accuracy
+	c.Evasion += b.Evasion
+	c.CritChance += b.CritChance
+	if b.AttackRange > c.AttackRange {
+		c.AttackRange = b.AttackRange
+	}
+	if b.AttackIntervalMs > 0 {
+		c.AttackIntervalMs = b.AttackIntervalMs
+	}
+	return c
+}
+
+// derivedStats is everything that is calculated from attributes and equipment
+type derivedStats struct {
+	attributes     attributes
+	combat         combatStats
+	maxHP          int
+	moveIntervalMs int
+}
+
+// calculateStats derives the combat and movement stats from the base attributes, level and the equipped items
+func calculateStats(base attributes, level int, equipment map[string]string) derivedStats {
+	levelAttributes, levelHP := ServerInstance.progression.bonuses(level)
+	total := base.add(levelAttributes)
+	bonuses := combatStats{}
+	for _, id := range equipment {
+		if t, found := ServerInstance.itemTemplates[id]; found {
+			total = total.add(t.Modifiers)
+			bonuses = bonuses.add(t.Bonuses)
+		}
+	}
+
+	combat := combatStats{
+		Attack:     3 + total.Strength/2,
+		Defence:    2 + total.Vitality/4,
+		Accuracy:   total.Perception,
+		Evasion:    total.Agility / 2,
+		CritChance: total.Perception / 2,
+	}.add(bonuses)
+
+	moveInterval := playerMoveIntervalMs + (defaultAttribute-total.Agility)*moveIntervalPerAgility
+	if moveInterval < minPlayerMoveInterval {
+		moveInterval = minPlayerMoveInterval
+	}
+
+	return derivedStats{
+		attributes:     total,
+		combat:         combat,
+		maxHP:          baseMaxHP + total.Vitality*hpPerVitality + levelHP,
+		moveIntervalMs: moveInterval,
+	}
+}
+
+// recalculateStats refreshes the derived stats of the player and sends them their character sheet
+func (p *Player) recalculateStats() {
+	p.derived = calculateStats(p.attributes, p.level, p.equipment)
+	p.stats = p.derived.combat
+	p.maxhp = p.derived.maxHP
+	if p.hp > p.maxhp {
+		p.hp = p.maxhp
+	}
+	p.sendCharacterSheet()
+}
+
+func (p *Player) moveInterval() int {
+	if p.derived.moveIntervalMs > 0 {
+		return p.derived.moveIntervalMs
+	}
+	return playerMoveIntervalMs
+}
+
+/*
+sendCharacterSheet sends the player everything about their character
+
+*****************************
+CHARACTER SHEET STRUCTURE
+*****************************
+2 bytes + <n> bytes - player name
+2 bytes - uint16 level
+4 bytes - uint32 total experience
+4 bytes - uint32 experience needed for the next level, 0 at max level
+2 bytes - uint16 current hp
+2 bytes - uint16 max hp
+... 2 bytes - uint16 base strength, agility, vitality, perception
+... 2 bytes - uint16 total strength, agility, vitality, perception
+2 bytes - uint16 attack
+2 bytes - uint16 defence
+2 bytes - uint16 accuracy
+2 bytes - uint16 evasion
+2 bytes - uint16 critical hit chance
+2 bytes - uint16 move interval in milliseconds
+1 byte - number of equipment slots
+... 1 byte - slot index
+... 2 bytes + <n> bytes - item template id, empty when nothing is equipped
+*/
+func (p *Player) sendCharacterSheet() {
+	if p.connection == nil {
+		return
+	}
+
+	pkt := NewPacket(MsgCharacterSheet)
+	pkt.WriteString(p.name)
+	pkt.WriteUint16(uint16(p.level))
+	pkt.WriteUint32(uint32(p.experience))
+	pkt.WriteUint32(uint32(p.nextLevelExperience()))
+	pkt.WriteUint16(uint16(p.hp))
+	pkt.WriteUint16(uint16(p.maxhp))
+	for _, a := range []attributes{p.attributes, p.derived.attributes} {
+		pkt.WriteUint16(uint16(a.Strength))
+		pkt.WriteUint16(uint16(a.Agility))
+		pkt.WriteUint16(uint16(a.Vitality))
+		pkt.WriteUint16(uint16(a.Perception))
+	}
+	pkt.WriteUint16(uint16(p.stats.Attack))
+	pkt.WriteUint16(uint16(p.stats.Defence))
+	pkt.WriteUint16(uint16(p.stats.Accuracy))
+	pkt.WriteUint16(uint16(p.stats.Evasion))
+	pkt.WriteUint16(uint16(p.stats.CritChance))
+	pkt.WriteUint16(uint16(p.moveInterval()))
+
+	pkt.WriteIntByte(len(equipmentSlots))
+	for i, slot := range equipmentSlots {
+		pkt.WriteIntByte(i)
+		pkt.WriteString(p.equipment[slot])
+	}
+	sendMessageToConnection(p.connection, *pkt)
+}
@@ -0,0 +1,301 @@
+package game
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Telnet commands and options, see RFC 854, RFC 857, RFC 858 and RFC 1073
+const (
+	TELNET_IAC  = byte(255)
+	TELNET_DONT = byte(254)
+	TELNET_DO   = byte(253)
+	TELNET_WONT = byte(252)
+	TELNET_WILL = byte(251)
+	TELNET_SB   = byte(250)
+	TELNET_SE   = byte(240)
+
+	TELNET_OPT_ECHO = byte(1)
+	TELNET_OPT_SGA  = byte(3)
+	TELNET_OPT_NAWS = byte(31)
+)
+
+const (
+	telnetStateData = iota
+	telnetStateIAC
+	telnetStateOption
+	telnetStateSub
+	telnetStateSubIAC
+)
+
+const (
+	defaultTelnetWidth  = 80
+	defaultTelnetHeight = 24
+	maxTelnetLineLength = 512
+	// maxTelnetSubLength is more than any option we accept needs, NAWS only sends 5 bytes
+	maxTelnetSubLength = 32
+)
+
+// telnetSession is the text side of a connection made by a MUD client or nc. It is the transport of the
+// connection as well, every frame read is a line of text and every frame written is sent as is.
+type telnetSession struct {
+	conn    net.Conn
+	pending []string
+	width   int
+	height  int
+	colour  bool
+	busy    bool
+	state   int
+	command byte
+	sub     []byte
+	line    []byte
+	names   map[int64]string
+}
+
+func newTelnetSession(conn net.Conn) *telnetSession {
+	return &telnetSession{
+		conn:   conn,
+		width:  defaultTelnetWidth,
+		height: defaultTelnetHeight,
+		colour: true,
+		line:   make([]byte, 0),
+		names:  make(map[int64]string),
+	}
+}
+
+// AddTelnetConnection adds a plain text connection to the pool, the player is asked for their name first
+func (server *Server) AddTelnetConnection(conn net.Conn) *Connection {
+	session := newTelnetSession(conn)
+	newConnection := &Connection{
+		transport:     session,
+		timeConnected: time.Now(),
+		telnet:        session,
+		// Text clients never see coordinates, the map is drawn on the server
+		protocolVersion: PROTOCOL_VERSION_LATEST,
+	}
+
+	server.mu.Lock()
+	server.connectionsList = append(server.connectionsList, newConnection)
+	server.mu.Unlock()
+
+	// We suppress go ahead and let the client echo, the window size is needed to fit the map on screen
+	newConnection.sendBytes([]byte{
+		TELNET_IAC, TELNET_WILL, TELNET_OPT_SGA,
+		TELNET_IAC, TELNET_WONT, TELNET_OPT_ECHO,
+		TELNET_IAC, TELNET_DO, TELNET_OPT_NAWS,
+	})
+	newConnection.writeLines(
+		newConnection.telnet.paint(ansiBold+ansiYellow, fmt.Sprintf("Welcome to %s!", server.GetName())),
+		"",
+	)
+	newConnection.sendString("By what name do you wish to be known? ")
+
+	go newConnection.listenTelnet()
+	return newConnection
+}
+
+// listenTelnet runs every line the player types as a command
+func (connection *Connection) listenTelnet() {
+	for {
+		line, err := connection.transport.ReadFrame()
+		if err != nil {
+			connection.transport.Close()
+			ServerInstance.onClientConnectionClosed(connection, err)
+			return
+		}
+
+		connection.handleTelnetLine(string(line))
+	}
+}
+
+func (connection *Connection) handleTelnetLine(line string) {
+	ServerInstance.mu.Lock()
+	defer ServerInstance.mu.Unlock()
+
+	// A command that breaks must not take the server down, the same as a malformed packet
+	defer func() {
+		if r := recover(); r != nil {
+			connection.telnet.busy = false
+			log.Warn().Str("error", fmt.Sprint(r)).Str("line", line).Msg("Failed to handle telnet line")
+		}
+	}()
+	connection.onTelnetLine(line)
+}
+
+// ReadFrame returns the next complete line without the telnet negotiation in it
+func (t *telnetSession) ReadFrame() ([]byte, error) {
+	buf := make([]byte, 4096)
+	for len(t.pending) == 0 {
+		n, err := t.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		t.pending = append(t.pending, t.feed(buf[:n])...)
+	}
+
+	line := t.pending[0]
+	t.pending = t.pending[1:]
+	return []byte(line), nil
+}
+
+func (t *telnetSession) WriteFrame(frame []byte) error {
+	_, err := t.conn.Write(frame)
+	return err
+}
+
+func (t *telnetSession) Close() error {
+	return t.conn.Close()
+}
+
+func (t *telnetSession) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// feed strips telnet negotiation from the received bytes and returns the complete lines
+func (t *telnetSession) feed(data []byte) []string {
+	lines := make([]string, 0)
+	for _, b := range data {
+		switch t.state {
+		case telnetStateData:
+			switch {
+			case b == TELNET_IAC:
+				t.state = telnetStateIAC
+			case b == '\n':
+				lines = append(lines, strings.TrimRight(string(t.line), "\r"))
+				t.line = t.line[:0]
+			case b == '\b' || b == 127:
+				if len(t.line) > 0 {
+					t.line = t.line[:len(t.line)-1]
+				}
+			case b == '\r' || b == 0:
+			case len(t.line) < maxTelnetLineLength:
+				t.line = append(t.line, b)
+			}
+		case telnetStateIAC:
+			switch b {
+			case TELNET_IAC:
+				// An escaped 255 is data, nobody should be typing it though
+				t.state = telnetStateData
+			case TELNET_DO, TELNET_DONT, TELNET_WILL, TELNET_WONT:
+				t.command = b
+				t.state = telnetStateOption
+			case TELNET_SB:
+				t.sub = t.sub[:0]
+				t.state = telnetStateSub
+			default:
+				t.state = telnetStateData
+			}
+		case telnetStateOption:
+			t.negotiate(t.command, b)
+			t.state = telnetStateData
+		case telnetStateSub:
+			if b == TELNET_IAC {
+				t.state = telnetStateSubIAC
+			} else {
+				t.appendSub(b)
+			}
+		case telnetStateSubIAC:
+			if b == TELNET_SE {
+				t.subnegotiation()
+				t.state = telnetStateData
+			} else {
+				t.appendSub(b)
+				t.state = telnetStateSub
+			}
+		}
+	}
+	return lines
+}
+
+// negotiate answers the client, only the options we asked for are accepted
+func (t *telnetSession) negotiate(command, option byte) {
+	switch command {
+	case TELNET_DO:
+		if option == TELNET_OPT_SGA {
+			return
+		}
+		// That includes echo, the client should keep echoing whatever is typed
+		t.WriteFrame([]byte{TELNET_IAC, TELNET_WONT, option})
+	case TELNET_WILL:
+		if option == TELNET_OPT_NAWS {
+			return
+		}
+		t.WriteFrame([]byte{TELNET_IAC, TELNET_DONT, option})
+	}
+}
+
+// appendSub collects a subnegotiation byte, anything past maxTelnetSubLength is dropped and so is the whole
+// subnegotiation since it no longer has the length of anything we accept
+func (t *telnetSession) appendSub(b byte) {
+	if len(t.sub) < maxTelnetSubLength {
+		t.sub = append(t.sub, b)
+	}
+}
+
+// subnegotiation handles the window size reports of NAWS
+func (t *telnetSession) subnegotiation() {
+	if len(t.sub) != 5 || t.sub[0] != TELNET_OPT_NAWS {
+		return
+	}
+	width := int(t.sub[1])<<8 | int(t.sub[2])
+	height := int(t.sub[3])<<8 | int(t.sub[4])
+	if width > 0 {
+		t.width = width
+	}
+	if height > 0 {
+		t.height = height
+	}
+}
+
+// onTelnetLine logs the player in with the first line, a name optionally followed by the role token. Everything
+// afterwards is a command.
+func (connection *Connection) onTelnetLine(line string) {
+	if connection.player == nil {
+		name, token := strings.TrimSpace(line), ""
+		if fields := strings.Fields(line); len(fields) == 2 {
+			name, token = fields[0], fields[1]
+		}
+		player, reason := joinWorld(connection, name, token)
+		if player == nil {
+			connection.writeLines(reason + ".")
+			connection.sendString("By what name do you wish to be known? ")
+			return
+		}
+		connection.writeLines(fmt.Sprintf("Welcome, %s. Type 'help' for a list of commands.", player.name), "")
+		sendCommandOutput(connection, executeCommand(player, "look"))
+		return
+	}
+
+	// Everything sent while the command runs is part of its output, the prompt follows once at the end
+	connection.telnet.busy = true
+	if output := executeCommand(connection.player, line); len(output) > 0 {
+		sendCommandOutput(connection, output)
+	}
+	connection.telnet.busy = false
+	connection.telnetPrompt()
+}
+
+// telnetPrompt shows the health of the player in front of the cursor
+func (connection *Connection) telnetPrompt() {
+	p := connection.player
+	if p == nil || connection.telnet == nil || connection.telnet.busy {
+		return
+	}
+	connection.sendString(connection.telnet.paint(ansiGreen, fmt.Sprintf("[%d/%d hp] ", p.hp, p.maxhp)) + "> ")
+}
+
+// writeLines sends text to a telnet client, which expects CR LF line endings
+func (connection *Connection) writeLines(lines ...string) {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(strings.ReplaceAll(line, "\n", "\r\n"))
+		b.WriteString("\r\n")
+	}
+	if err := connection.transport.WriteFrame([]byte(b.String())); err != nil {
+		log.Debug().Err(err).Msg("Failed to write to telnet connection")
+	}
+}
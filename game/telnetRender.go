@@ -0,0 +1,237 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiWhite   = "\x1b[37m"
+)
+
+const (
+	defaultMapWidth  = 40
+	defaultMapHeight = 20
+)
+
+// paint wraps the text in an ANSI colour unless the player turned colours off
+func (t *telnetSession) paint(code, text string) string {
+	if t == nil || !t.colour {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// render turns a packet meant for a binary client into text, packets without a text form are dropped
+func (connection *Connection) render(packet Packet) {
+	t := connection.telnet
+	lines := make([]string, 0)
+
+	switch packet.Type {
+	case MsgCommandOutput:
+		lines = append(lines, string(packet.ReadBytes(uint32(packet.ReadUint16()))))
+	case MsgChatMessage:
+		lines = append(lines, connection.renderChat(&packet))
+	case MsgChatError:
+		lines = append(lines, t.paint(ansiRed, chatResultText[packet.ReadUint8()]))
+	case MsgCombatEvent:
+		lines = append(lines, connection.renderCombat(&packet)...)
+	case MsgEntitySpawn:
+		id := int64(packet.ReadUint64())
+		isPlayer := packet.ReadBoolean()
+		name := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+		t.names[id] = name
+		if isPlayer && connection.player != nil && id != connection.player.id {
+			lines = append(lines, t.paint(ansiCyan, fmt.Sprintf("%s arrives.", name)))
+		}
+	case MsgEntityDespawn:
+		id := int64(packet.ReadUint64())
+		packet.ReadBytes(uint32(packet.ReadUint16()))
+		reason := packet.ReadUint8()
+		if name, found := t.names[id]; found && reason == DESPAWN_REASON_LEFT {
+			lines = append(lines, t.paint(ansiCyan, fmt.Sprintf("%s leaves.", name)))
+		}
+		delete(t.names, id)
+	case MsgExperienceGain:
+		lines = append(lines, t.paint(ansiYellow, fmt.Sprintf("You gain %d experience.", packet.ReadUInt32())))
+	case MsgLevelUp:
+		id := int64(packet.ReadUint64())
+		level := packet.ReadUint16()
+		if connection.player != nil && id == connection.player.id {
+			lines = append(lines, t.paint(ansiBold+ansiYellow, fmt.Sprintf("You are now level %d!", level)))
+		} else {
+			lines = append(lines, t.paint(ansiYellow, fmt.Sprintf("%s is now level %d.", connection.nameOf(id), level)))
+		}
+	case MsgPartyInvite:
+		packet.ReadUint64()
+		name := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+		lines = append(lines, t.paint(ansiGreen, fmt.Sprintf("%s invites you to a party, type 'accept %s' to join.", name, name)))
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+	connection.writeLines(lines...)
+	connection.telnetPrompt()
+}
+
+// nameOf returns the name of an entity the client has been told about
+func (connection *Connection) nameOf(id int64) string {
+	if connection.player != nil && id == connection.player.id {
+		return "you"
+	}
+	if name, found := connection.telnet.names[id]; found {
+		return name
+	}
+	return "something"
+}
+
+func (connection *Connection) renderChat(packet *Packet) string {
+	channel := packet.ReadUint8()
+	id := int64(packet.ReadUint64())
+	name := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	message := string(packet.ReadBytes(uint32(packet.ReadUint16())))
+	self := connection.player != nil && id == connection.player.id
+
+	t := connection.telnet
+	switch channel {
+	case CHAT_CHANNEL_SHOUT:
+		if self {
+			return t.paint(ansiYellow, fmt.Sprintf("You shout: %s", message))
+		}
+		return t.paint(ansiYellow, fmt.Sprintf("%s shouts: %s", name, message))
+	case CHAT_CHANNEL_WHISPER:
+		if self {
+			return t.paint(ansiMagenta, fmt.Sprintf("You whisper: %s", message))
+		}
+		return t.paint(ansiMagenta, fmt.Sprintf("%s whispers: %s", name, message))
+	case CHAT_CHANNEL_PARTY:
+		return t.paint(ansiGreen, fmt.Sprintf("[party] %s: %s", name, message))
+	}
+	if self {
+		return t.paint(ansiWhite, fmt.Sprintf("You say: %s", message))
+	}
+	return t.paint(ansiWhite, fmt.Sprintf("%s says: %s", name, message))
+}
+
+func (connection *Connection) renderCombat(packet *Packet) []string {
+	attacker := connection.nameOf(int64(packet.ReadUint64()))
+	target := connection.nameOf(int64(packet.ReadUint64()))
+	packet.ReadUint8()
+	result := packet.ReadUint8()
+	damage := packet.ReadUint16()
+	packet.ReadUint16()
+	died := packet.ReadBoolean()
+
+	t := connection.telnet
+	lines := make([]string, 0, 2)
+	switch result {
+	case ATTACK_RESULT_MISS:
+		lines = append(lines, fmt.Sprintf("%s misses %s.", capitalise(attacker), target))
+	case ATTACK_RESULT_CRIT:
+		lines = append(lines, t.paint(ansiBold+ansiRed, fmt.Sprintf("%s critically hits %s for %d damage!", capitalise(attacker), target, damage)))
+	default:
+		lines = append(lines, t.paint(ansiRed, fmt.Sprintf("%s hits %s for %d damage.", capitalise(attacker), target, damage)))
+	}
+	if died {
+		if target == "you" {
+			lines = append(lines, t.paint(ansiBold+ansiRed, "You die."))
+		} else {
+			lines = append(lines, t.paint(ansiBold, fmt.Sprintf("%s dies.", capitalise(target))))
+		}
+	}
+	return lines
+}
+
+func capitalise(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// asciiMap draws the part of the room around the player that fits in the given size
+func asciiMap(room *Room, player *Player, width, height int, t *telnetSession) []string {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	if width > room.Width {
+		width = room.Width
+	}
+	if height > room.Height {
+		height = room.Height
+	}
+
+	// Keep the player in the middle unless that would scroll past the edge of the room
+	left := clamp(player.position.X-width/2, 0, room.Width-width)
+	top := clamp(player.position.Y-height/2, 0, room.Height-height)
+
+	overlay := make(map[Vector2]string)
+	for _, e := range room.entities {
+		if e.getID() != player.id && player.canSee(room, e) {
+			overlay[e.getPosition()] = entitySymbol(e, t)
+		}
+	}
+	overlay[room.Exit.LocationInRoom] = t.paint(ansiMagenta, ">")
+	overlay[player.position] = t.paint(ansiBold+ansiWhite, "@")
+
+	lines := make([]string, 0, height)
+	for y := top; y < top+height; y++ {
+		var b strings.Builder
+		for x := left; x < left+width; x++ {
+			if !player.hasExplored(room, x, y) {
+				b.WriteString(" ")
+				continue
+			}
+			if symbol, found := overlay[Vector2{x, y}]; found {
+				b.WriteString(symbol)
+				continue
+			}
+			definition := tileTypeOf(room.Tiles[x][y].Type)
+			symbol := definition.Glyph
+			if definition.Opaque {
+				symbol = t.paint(ansiBlue, symbol)
+			} else if definition.Liquid {
+				symbol = t.paint(ansiCyan, symbol)
+			}
+			b.WriteString(symbol)
+		}
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+func entitySymbol(e entity, t *telnetSession) string {
+	switch v := e.(type) {
+	case *NPC:
+		symbol := "n"
+		if v.template.ID != "" {
+			symbol = strings.ToLower(v.template.ID[:1])
+		}
+		if v.template.Kind == NPC_KIND_MONSTER {
+			return t.paint(ansiRed, symbol)
+		}
+		return t.paint(ansiGreen, symbol)
+	case *groundItem:
+		return t.paint(ansiYellow, "*")
+	}
+	return t.paint(ansiCyan, "P")
+}
+
+func clamp(v, min, max int) int {
+	if v > max {
+		v = max
+	}
+	if v < min {
+		v = min
+	}
+	return v
+}
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TILE_FLAG_DAMAGING = uint8(1 << iota)
+	TILE_FLAG_LIQUID
+)
+
+// tileDefinition describes a kind of tile. Passable is only the default for new tiles, every tile in a room
+// keeps its own passability so builders can make exceptions.
+type tileDefinition struct {
+	ID           uint8  `json:"id"`
+	Name         string `json:"name"`
+	Passable     bool   `json:"passable"`
+	MovementCost int    `json:"movement_cost"`
+	Opaque       bool   `json:"opaque"`
+	// Damaging and Liquid are only sent to clients for now, the server does not act on them
+	Damaging bool `json:"damaging"`
+	Liquid   bool `json:"liquid"`
+	// Glyph is how the tile is drawn on the telnet map
+	Glyph string `json:"glyph"`
+}
+
+// unknownTileType stands in for tiles whose type was removed from the tile data file
+var unknownTileType = tileDefinition{Name: "Unknown", MovementCost: 1, Opaque: true, Glyph: "?"}
+
+func (t *tileDefinition) flags() uint8 {
+	var flags uint8
+	if t.Damaging {
+		flags |= TILE_FLAG_DAMAGING
+	}
+	if t.Liquid {
+		flags |= TILE_FLAG_LIQUID
+	}
+	return flags
+}
+
+// tileTypeOf returns the definition of the tile type
+func tileTypeOf(id uint8) *tileDefinition {
+	if definition, found := ServerInstance.tileTypes[id]; found {
+		return definition
+	}
+	return &unknownTileType
+}
+
+// newTile makes a tile of the given type with the default passability of the type
+func newTile(id uint8, position Vector2) Tile {
+	return Tile{Type: id, IsPassable: tileTypeOf(id).Passable, Position: position}
+}
+
+// loadTileTypes reads tile definitions from the data directory, creating a default file if there is none
+func loadTileTypes(dataPath string) (map[uint8]*tileDefinition, error) {
+	filePath := path.Join(dataPath, "tiles.json")
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		log.Info().Str("filepath", filePath).Msg("Tile data file does not exist, creating a new one")
+		defaults := []tileDefinition{
+			{ID: TILE_TYPE_WALL, Name: "Wall", MovementCost: 1, Opaque: true, Glyph: "#"},
+			{ID: TILE_TYPE_DIRT, Name: "Dirt", Passable: true, MovementCost: 1, Glyph: "."},
+			{ID: TILE_TYPE_PORTAL, Name: "Portal", Passable: true, MovementCost: 1, Glyph: "O"},
+			{ID: TILE_TYPE_AIR, Name: "Air", MovementCost: 1, Glyph: " "},
+			{ID: TILE_TYPE_WATER, Name: "Shallow water", Passable: true, MovementCost: 3, Liquid: true, Glyph: "~"},
+		}
+		jData, _ := json.MarshalIndent(defaults, "", " ")
+		if err := ioutil.WriteFile(filePath, jData, 0666); err != nil {
+			log.Warn().Err(err).Msg("Failed to write tile data file to disk")
+		}
+	}
+
+	fData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read tile data file from disk")
+		return nil, err
+	}
+
+	list := make([]*tileDefinition, 0)
+	if err := json.Unmarshal(fData, &list); err != nil {
+		log.Warn().Err(err).Msg("failed to unmarshal tile data")
+		return nil, err
+	}
+
+	types := make(map[uint8]*tileDefinition, len(list))
+	for _, t := range list {
+		if t == nil {
+			log.Warn().Msg("Skipping an empty tile type in the tile data file")
+			continue
+		}
+		if _, found := types[t.ID]; found {
+			log.Warn().Uint8("tile", t.ID).Msg("Tile type is defined more than once, the last definition wins")
+		}
+		if t.MovementCost < 1 {
+			t.MovementCost = 1
+		}
+		if t.Glyph == "" {
+			t.Glyph = "?"
+		}
+		types[t.ID] = t
+	}
+	for _, id := range []uint8{TILE_TYPE_WALL, TILE_TYPE_DIRT} {
+		if _, found := types[id]; !found {
+			log.Warn().Uint8("tile", id).Msg("Tile type used by the room generator is not defined")
+		}
+	}
+	log.Debug().Int("count", len(types)).Msg("Loaded tile types")
+	return types, nil
+}
+
+/*
+*****************************
+TILE DEFINITIONS STRUCTURE
+*****************************
+2 bytes - uint16 number of tile types
+... 1 byte - tile type id
+... 2 bytes + <n> bytes - name
+... 1 byte - bool passable by default
+... 1 byte - movement cost uint8, 1 is normal ground
+... 1 byte - bool blocks vision
+... 1 byte - flags (1 - damaging, 2 - liquid)
+
+Sent before the join response so clients know how to show every tile in the room data.
+*/
+func sendTileTypes(connection *Connection) {
+	pkt := NewPacket(MsgTileDefinitions)
+	pkt.WriteUint16(uint16(len(ServerInstance.tileTypes)))
+	for id := 0; id <= 255; id++ {
+		definition, found := ServerInstance.tileTypes[uint8(id)]
+		if !found {
+			continue
+		}
+		cost := definition.MovementCost
+		if cost > 255 {
+			cost = 255
+		}
+		pkt.WriteUint8(definition.ID).
+			WriteString(definition.Name).
+			WriteBool(definition.Passable).
+			WriteUint8(uint8(cost)).
+			WriteBool(definition.Opaque).
+			WriteUint8(definition.flags())
+	}
+	sendMessageToConnection(connection, *pkt)
+}
@@ -0,0 +1,23 @@
+package game
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestLoadTileTypesSkipsEmptyEntries(t *testing.T) {
+	dataPath := t.TempDir()
+	tiles := `[null, {"id": 1, "name": "Dirt", "passable": true}]`
+	if err := ioutil.WriteFile(path.Join(dataPath, "tiles.json"), []byte(tiles), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	types, err := loadTileTypes(dataPath)
+	if err != nil {
+		t.Fatalf("Expected the tile data to load but got %v", err)
+	}
+	if len(types) != 1 || types[TILE_TYPE_DIRT] == nil {
+		t.Fatalf("Expected only the dirt tile type to be loaded but got %d types", len(types))
+	}
+}
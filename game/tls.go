@@ -0,0 +1,180 @@
+package game
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultCertFile      = "server.crt"
+	defaultKeyFile       = "server.key"
+	devCertificateExpiry = 365 * 24 * time.Hour
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// certificateStore hands out the current certificate and loads it again whenever the files change on disk,
+// replacing the certificate and key is all it takes to rotate them
+type certificateStore struct {
+	certFile string
+	keyFile  string
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTime  time.Time
+}
+
+func (s *certificateStore) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	certInfo, err := os.Stat(s.certFile)
+	if err != nil {
+		return s.current(err)
+	}
+	keyInfo, err := os.Stat(s.keyFile)
+	if err != nil {
+		return s.current(err)
+	}
+
+	modTime := certInfo.ModTime()
+	if keyInfo.ModTime().After(modTime) {
+		modTime = keyInfo.ModTime()
+	}
+	if s.cert != nil && !modTime.After(s.modTime) {
+		return s.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return s.current(err)
+	}
+	if s.cert != nil {
+		log.Info().Str("certificate", s.certFile).Msg("Reloaded TLS certificate")
+	}
+	s.cert = &cert
+	s.modTime = modTime
+	return s.cert, nil
+}
+
+// current keeps serving the last good certificate when the files on disk are broken or half written
+func (s *certificateStore) current(err error) (*tls.Certificate, error) {
+	if s.cert == nil {
+		return nil, err
+	}
+	log.Warn().Err(err).Str("certificate", s.certFile).Msg("Failed to reload TLS certificate, keeping the old one")
+	return s.cert, nil
+}
+
+// TLSConfig builds the TLS configuration of the game listener, nil means TLS is disabled
+func (server *Server) TLSConfig() (*tls.Config, error) {
+	conf := server.config.TLS
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	certFile, keyFile := conf.CertFile, conf.KeyFile
+	if certFile == "" {
+		certFile = defaultCertFile
+	}
+	if keyFile == "" {
+		keyFile = defaultKeyFile
+	}
+	// Relative paths live in the config directory
+	if !path.IsAbs(certFile) {
+		certFile = path.Join(server.configPath, certFile)
+	}
+	if !path.IsAbs(keyFile) {
+		keyFile = path.Join(server.configPath, keyFile)
+	}
+
+	minVersion := uint16(tls.VersionTLS12)
+	if conf.MinVersion != "" {
+		v, found := tlsVersions[conf.MinVersion]
+		if !found {
+			return nil, fmt.Errorf("unknown TLS version %s", conf.MinVersion)
+		}
+		minVersion = v
+	}
+
+	if _, err := os.Stat(certFile); os.IsNotExist(err) && conf.SelfSigned {
+		if err := generateDevCertificate(certFile, keyFile, server.config.ServerAddress); err != nil {
+			return nil, err
+		}
+		log.Warn().Str("certificate", certFile).Msg("Generated a self signed certificate, only use it for development")
+	}
+
+	store := &certificateStore{certFile: certFile, keyFile: keyFile}
+	// Fail on start rather than on the first handshake
+	if _, err := store.getCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: store.getCertificate,
+	}, nil
+}
+
+// generateDevCertificate writes a self signed certificate for localhost and the server address
+func generateDevCertificate(certFile, keyFile, address string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Aeon of Strife development"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(devCertificateExpiry),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(address); ip != nil && !ip.IsLoopback() {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if ip == nil && address != "" {
+		template.DNSNames = append(template.DNSNames, address)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+}
@@ -0,0 +1,131 @@
+package game
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxFrameLength is the largest frame a client may send, anything bigger is treated as a broken stream
+	maxFrameLength      = 1024 * 1024
+	tlsHandshakeTimeout = 10 * time.Second
+)
+
+var ErrFrameTooLarge = errors.New("frame exceeds the maximum frame length")
+
+// Transport moves whole frames between the server and a client. A frame is the packet type followed by
+// the payload, how frames are delimited on the wire is up to the transport.
+type Transport interface {
+	ReadFrame() ([]byte, error)
+	WriteFrame(frame []byte) error
+	Close() error
+	RemoteAddr() net.Addr
+}
+
+// streamTransport frames packets on a byte stream with a 4 byte little endian length prefix
+type streamTransport struct {
+	conn    net.Conn
+	header  [4]byte
+	writeMu sync.Mutex
+	// Frames bigger than the threshold are compressed once the client asked for it, 0 turns compression off
+	compressThreshold int
+	compressor        *flate.Writer
+	compressBuf       bytes.Buffer
+}
+
+func newStreamTransport(conn net.Conn) *streamTransport {
+	return &streamTransport{conn: conn}
+}
+
+// NewTCPTransport frames packets on a plain TCP connection
+func NewTCPTransport(conn net.Conn) Transport {
+	return newStreamTransport(conn)
+}
+
+// NewTLSTransport wraps the connection in TLS and completes the handshake, slow clients get tlsHandshakeTimeout
+func NewTLSTransport(conn net.Conn, config *tls.Config) (Transport, error) {
+	tlsConn := tls.Server(conn, config)
+	tlsConn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return newStreamTransport(tlsConn), nil
+}
+
+// NewWebSocketTransport sends every frame as a single binary message using the same layout as TCP
+func NewWebSocketTransport(ws *websocket.Conn) Transport {
+	return newStreamTransport(newWebsocketConn(ws))
+}
+
+// NewPipeTransport returns an in memory transport and the client end of it, mostly useful for tests
+func NewPipeTransport() (Transport, net.Conn) {
+	server, client := net.Pipe()
+	return newStreamTransport(server), client
+}
+
+func (t *streamTransport) ReadFrame() ([]byte, error) {
+	if _, err := io.ReadFull(t.conn, t.header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(t.header[:])
+	compressed := length&frameFlagCompressed != 0
+	length &^= frameFlagCompressed
+	if length > maxFrameLength {
+		return nil, ErrFrameTooLarge
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(t.conn, frame); err != nil {
+		return nil, err
+	}
+	if compressed {
+		return decompressFrame(frame)
+	}
+	return frame, nil
+}
+
+// WriteFrame writes the length and the frame in one go so message based connections get a single message
+func (t *streamTransport) WriteFrame(frame []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	flags := uint32(0)
+	if t.compressThreshold > 0 && len(frame) > t.compressThreshold {
+		compressed, err := t.compressFrame(frame)
+		if err != nil {
+			return err
+		}
+		// Some frames, like already compressed data, don't get any smaller
+		if len(compressed) < len(frame) {
+			frame = compressed
+			flags = frameFlagCompressed
+		}
+	}
+
+	data := make([]byte, 4, 4+len(frame))
+	binary.LittleEndian.PutUint32(data, uint32(len(frame))|flags)
+	data = append(data, frame...)
+
+	_, err := t.conn.Write(data)
+	return err
+}
+
+func (t *streamTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *streamTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultWebSocketPath = "/ws"
+
+// websocketConn lets a websocket pass for a net.Conn, every write is sent as a single binary message
+// and reads run through the messages one after the other
+type websocketConn struct {
+	ws      *websocket.Conn
+	reader  io.Reader
+	writeMu sync.Mutex
+}
+
+func newWebsocketConn(ws *websocket.Conn) *websocketConn {
+	return &websocketConn{ws: ws}
+}
+
+func (c *websocketConn) Read(b []byte) (int, error) {
+	for {
+		if c.reader == nil {
+			messageType, reader, err := c.ws.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			// Browsers can't be trusted to not send text, we only speak binary
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			c.reader = reader
+		}
+
+		n, err := c.reader.Read(b)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *websocketConn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.ws.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *websocketConn) Close() error {
+	return c.ws.Close()
+}
+
+func (c *websocketConn) LocalAddr() net.Addr {
+	return c.ws.LocalAddr()
+}
+
+func (c *websocketConn) RemoteAddr() net.Addr {
+	return c.ws.RemoteAddr()
+}
+
+func (c *websocketConn) SetDeadline(t time.Time) error {
+	if err := c.ws.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.ws.SetWriteDeadline(t)
+}
+
+func (c *websocketConn) SetReadDeadline(t time.Time) error {
+	return c.ws.SetReadDeadline(t)
+}
+
+func (c *websocketConn) SetWriteDeadline(t time.Time) error {
+	return c.ws.SetWriteDeadline(t)
+}
+
+func (server *Server) GetWebSocketPort() int {
+	return server.config.WebSocket.Port
+}
+
+// WebSocketHandler upgrades browser connections and hands them over to the same pipeline as native clients
+func (server *Server) WebSocketHandler() http.Handler {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     server.allowedOrigin,
+	}
+
+	path := server.config.WebSocket.Path
+	if path == "" {
+		path = defaultWebSocketPath
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// The upgrader has already answered with an error
+			log.Debug().Err(err).Str("connection", r.RemoteAddr).Msg("Failed to upgrade websocket connection")
+			return
+		}
+		log.Info().Str("connection", ws.RemoteAddr().String()).Msg("Incoming websocket connection")
+		server.AddConnection(NewWebSocketTransport(ws))
+	})
+	return mux
+}
+
+// allowedOrigin checks the origin of the page that opened the websocket, no configured origins allows all of them
+func (server *Server) allowedOrigin(r *http.Request) bool {
+	origins := server.config.WebSocket.AllowedOrigins
+	if len(origins) == 0 {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	for _, o := range origins {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -5,5 +5,6 @@ go 1.16
 require (
 	github.com/Entrio/subenv v0.0.0-20210211031353-9ddad865e314
 	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/rs/zerolog v1.25.0
 )
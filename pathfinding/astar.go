@@ -0,0 +1,207 @@
+package pathfinding
+
+import "container/heap"
+
+// Point is a tile coordinate on a grid
+type Point struct {
+	X int
+	Y int
+}
+
+// Grid is anything that can tell the size of its tile map and whether a tile can be walked on
+type Grid interface {
+	Size() (width, height int)
+	IsPassable(x, y int) bool
+}
+
+// WeightedGrid is a grid where some tiles are slower to cross, a step onto a tile costs MovementCost times
+// as much as a step onto normal ground. Costs below 1 count as 1.
+type WeightedGrid interface {
+	Grid
+	MovementCost(x, y int) int
+}
+
+// Options tune a single path search
+type Options struct {
+	// Diagonal allows 8 directional movement, corners can not be cut
+	Diagonal bool
+	// Blocked reports dynamic obstacles such as occupying entities. The start and goal are never blocked.
+	Blocked func(x, y int) bool
+	// MaxNodes limits the amount of expanded nodes, 0 means no limit
+	MaxNodes int
+}
+
+var (
+	straight = []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+	diagonal = []Point{{1, -1}, {1, 1}, {-1, 1}, {-1, -1}}
+)
+
+const (
+	costStraight = 10
+	costDiagonal = 14
+)
+
+type node struct {
+	point Point
+	g     int
+	f     int
+	index int
+}
+
+type openList []*node
+
+func (o openList) Len() int            { return len(o) }
+func (o openList) Less(i, j int) bool  { return o[i].f < o[j].f }
+func (o openList) Swap(i, j int)       { o[i], o[j] = o[j], o[i]; o[i].index = i; o[j].index = j }
+func (o *openList) Push(x interface{}) { n := x.(*node); n.index = len(*o); *o = append(*o, n) }
+func (o *openList) Pop() interface{} {
+	old := *o
+	n := old[len(old)-1]
+	*o = old[:len(old)-1]
+	return n
+}
+
+// FindPath runs A* between two points. The returned path excludes the start and includes the goal.
+func FindPath(grid Grid, from, to Point, opts Options) ([]Point, bool) {
+	if from == to {
+		return []Point{}, true
+	}
+	if !walkable(grid, to, from, to, opts) {
+		return nil, false
+	}
+
+	open := &openList{}
+	nodes := map[Point]*node{}
+	cameFrom := map[Point]Point{}
+	closed := map[Point]bool{}
+
+	start := &node{point: from, f: heuristic(from, to, opts.Diagonal)}
+	nodes[from] = start
+	heap.Push(open, start)
+
+	expanded := 0
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*node)
+		if current.point == to {
+			return buildPath(cameFrom, from, to), true
+		}
+		closed[current.point] = true
+
+		expanded++
+		if opts.MaxNodes > 0 && expanded > opts.MaxNodes {
+			return nil, false
+		}
+
+		for _, step := range neighbours(grid, current.point, from, to, opts) {
+			if closed[step.point] {
+				continue
+			}
+			g := current.g + step.cost
+			n, seen := nodes[step.point]
+			if seen && g >= n.g {
+				continue
+			}
+			cameFrom[step.point] = current.point
+			if !seen {
+				n = &node{point: step.point}
+				nodes[step.point] = n
+				n.g = g
+				n.f = g + heuristic(step.point, to, opts.Diagonal)
+				heap.Push(open, n)
+			} else {
+				n.g = g
+				n.f = g + heuristic(step.point, to, opts.Diagonal)
+				heap.Fix(open, n.index)
+			}
+		}
+	}
+
+	return nil, false
+}
+
+type neighbour struct {
+	point Point
+	cost  int
+}
+
+func neighbours(grid Grid, p, from, to Point, opts Options) []neighbour {
+	list := make([]neighbour, 0, 8)
+	for _, d := range straight {
+		next := Point{p.X + d.X, p.Y + d.Y}
+		if walkable(grid, next, from, to, opts) {
+			list = append(list, neighbour{next, costStraight * movementCost(grid, next)})
+		}
+	}
+
+	if !opts.Diagonal {
+		return list
+	}
+
+	for _, d := range diagonal {
+		next := Point{p.X + d.X, p.Y + d.Y}
+		// Don't allow cutting corners of walls
+		if !walkable(grid, Point{p.X + d.X, p.Y}, from, to, opts) || !walkable(grid, Point{p.X, p.Y + d.Y}, from, to, opts) {
+			continue
+		}
+		if walkable(grid, next, from, to, opts) {
+			list = append(list, neighbour{next, costDiagonal * movementCost(grid, next)})
+		}
+	}
+	return list
+}
+
+func walkable(grid Grid, p, from, to Point, opts Options) bool {
+	width, height := grid.Size()
+	if p.X < 0 || p.Y < 0 || p.X >= width || p.Y >= height {
+		return false
+	}
+	if !grid.IsPassable(p.X, p.Y) {
+		return false
+	}
+	if opts.Blocked != nil && p != from && p != to && opts.Blocked(p.X, p.Y) {
+		return false
+	}
+	return true
+}
+
+func movementCost(grid Grid, p Point) int {
+	weighted, ok := grid.(WeightedGrid)
+	if !ok {
+		return 1
+	}
+	if cost := weighted.MovementCost(p.X, p.Y); cost > 1 {
+		return cost
+	}
+	return 1
+}
+
+func heuristic(a, b Point, diagonalMovement bool) int {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if !diagonalMovement {
+		return costStraight * (dx + dy)
+	}
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return costDiagonal*dy + costStraight*(dx-dy)
+}
+
+func buildPath(cameFrom map[Point]Point, from, to Point) []Point {
+	path := []Point{to}
+	for current := to; cameFrom[current] != from; {
+		current = cameFrom[current]
+		path = append(path, current)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
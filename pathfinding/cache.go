@@ -0,0 +1,92 @@
+package pathfinding
+
+import "sync"
+
+// MaxCachedPaths is how many paths a cache holds before it starts over, targets that keep moving would
+// otherwise add a path on every step
+const MaxCachedPaths = 1024
+
+type cacheKey struct {
+	from     Point
+	to       Point
+	diagonal bool
+}
+
+// Cache remembers paths for a single grid until its revision changes.
+// Paths are cached without dynamic obstacles and are only reused when none of those block them.
+type Cache struct {
+	mu       sync.Mutex
+	revision uint64
+	paths    map[cacheKey][]Point
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		paths: make(map[cacheKey][]Point),
+	}
+}
+
+// FindPath returns a cached path when possible, otherwise it searches and stores the result
+func (c *Cache) FindPath(grid Grid, revision uint64, from, to Point, opts Options) ([]Point, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.revision != revision {
+		c.paths = make(map[cacheKey][]Point)
+		c.revision = revision
+	}
+
+	key := cacheKey{from, to, opts.Diagonal}
+	path, found := c.paths[key]
+	if !found {
+		if len(c.paths) >= MaxCachedPaths {
+			c.paths = make(map[cacheKey][]Point)
+		}
+		static := opts
+		static.Blocked = nil
+		var ok bool
+		if path, ok = FindPath(grid, from, to, static); ok {
+			c.paths[key] = path
+		} else {
+			// An unreachable goal stays unreachable until the grid changes
+			c.paths[key] = nil
+		}
+	}
+
+	if path == nil {
+		return nil, false
+	}
+	if opts.Blocked == nil || !isBlocked(path, to, opts.Blocked) {
+		return copyPath(path), true
+	}
+	return FindPath(grid, from, to, opts)
+}
+
+// Len returns how many paths are cached
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.paths)
+}
+
+// Invalidate drops every cached path
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	c.paths = make(map[cacheKey][]Point)
+	c.mu.Unlock()
+}
+
+func isBlocked(path []Point, to Point, blocked func(x, y int) bool) bool {
+	for _, p := range path {
+		if p != to && blocked(p.X, p.Y) {
+			return true
+		}
+	}
+	return false
+}
+
+func copyPath(path []Point) []Point {
+	c := make([]Point, len(path))
+	copy(c, path)
+	return c
+}
@@ -0,0 +1,27 @@
+package pathfinding
+
+// Reachable flood fills the grid from one point and reports whether the other one can be reached.
+// Dynamic obstacles in the options are ignored, only the tiles matter.
+func Reachable(grid Grid, from, to Point, opts Options) bool {
+	opts.Blocked = nil
+	if !walkable(grid, from, from, to, opts) || !walkable(grid, to, from, to, opts) {
+		return false
+	}
+
+	visited := map[Point]bool{from: true}
+	queue := []Point{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			return true
+		}
+		for _, n := range neighbours(grid, current, from, to, opts) {
+			if !visited[n.point] {
+				visited[n.point] = true
+				queue = append(queue, n.point)
+			}
+		}
+	}
+	return false
+}
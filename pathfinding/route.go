@@ -0,0 +1,37 @@
+package pathfinding
+
+// RoomGraph describes how rooms are connected through their exits
+type RoomGraph interface {
+	Destinations(roomID string) []string
+}
+
+// FindRoute does a breadth first search over the room graph. The returned route includes both ends.
+func FindRoute(graph RoomGraph, from, to string) ([]string, bool) {
+	if from == to {
+		return []string{from}, true
+	}
+
+	cameFrom := map[string]string{from: from}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, next := range graph.Destinations(current) {
+			if _, seen := cameFrom[next]; seen {
+				continue
+			}
+			cameFrom[next] = current
+			if next == to {
+				route := []string{to}
+				for next != from {
+					next = cameFrom[next]
+					route = append([]string{next}, route...)
+				}
+				return route, true
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil, false
+}
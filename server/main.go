@@ -1,8 +1,10 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 
 	"github.com/Entrio/aeonofstrife/game"
@@ -11,7 +13,8 @@ import (
 )
 
 var (
-	s *game.Server
+	s         *game.Server
+	tlsConfig *tls.Config
 )
 
 /**
@@ -30,6 +33,11 @@ func main() {
 		panic(err)
 	}
 
+	tlsConfig, err = s.TLSConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up TLS")
+	}
+
 	port := fmt.Sprintf("%s:%d", s.GetAddress(), s.GetPort())
 	tcpAddr, err := net.ResolveTCPAddr("tcp4", port)
 	if err != nil {
@@ -47,8 +55,42 @@ func main() {
 		}
 	}()
 
+	if s.GetTelnetPort() > 0 {
+		telnetAddr, err := net.ResolveTCPAddr("tcp4", fmt.Sprintf("%s:%d", s.GetAddress(), s.GetTelnetPort()))
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to resolve telnet address")
+		}
+		telnetListener, err := net.ListenTCP("tcp", telnetAddr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to listen for telnet connections")
+		}
+		defer telnetListener.Close()
+		log.Info().Str("address", telnetListener.Addr().String()).Msg("Telnet listener started")
+		go listenForTelnetConnections(telnetListener)
+	}
+
+	if s.GetWebSocketPort() > 0 {
+		webServer := &http.Server{
+			Addr:      fmt.Sprintf("%s:%d", s.GetAddress(), s.GetWebSocketPort()),
+			Handler:   s.WebSocketHandler(),
+			TLSConfig: tlsConfig,
+		}
+		defer webServer.Close()
+		go func() {
+			log.Info().Str("address", webServer.Addr).Bool("tls", tlsConfig != nil).Msg("WebSocket listener started")
+			serve := webServer.ListenAndServe
+			if tlsConfig != nil {
+				// The certificates come from the TLS config
+				serve = func() error { return webServer.ListenAndServeTLS("", "") }
+			}
+			if err := serve(); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("Failed to listen for websocket connections")
+			}
+		}()
+	}
+
 	s.Start()
-	log.Info().Str("address", listener.Addr().String()).Msg("Server started")
+	log.Info().Str("address", listener.Addr().String()).Bool("tls", tlsConfig != nil).Msg("Server started")
 	listenForConnections(listener)
 
 }
@@ -67,5 +109,31 @@ func listenForConnections(listener *net.TCPListener) {
 
 func newDescriptor(connection net.Conn) {
 	log.Info().Str("connection", connection.RemoteAddr().String()).Msg("Incoming network connection")
-	s.AddConnection(connection)
+	if tlsConfig == nil {
+		s.AddConnection(game.NewTCPTransport(connection))
+		return
+	}
+
+	// Handshakes happen off the accept loop so a slow client can't hold up everyone else
+	go func() {
+		transport, err := game.NewTLSTransport(connection, tlsConfig)
+		if err != nil {
+			log.Warn().Err(err).Str("connection", connection.RemoteAddr().String()).Msg("TLS handshake failed")
+			return
+		}
+		s.AddConnection(transport)
+	}()
+}
+
+func listenForTelnetConnections(listener *net.TCPListener) {
+	log.Debug().Msg("Telnet listener now is listening for connections")
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to accept telnet connection")
+			continue
+		}
+		log.Info().Str("connection", conn.RemoteAddr().String()).Msg("Incoming telnet connection")
+		s.AddTelnetConnection(conn)
+	}
 }
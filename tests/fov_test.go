@@ -0,0 +1,63 @@
+package copy_test
+
+import (
+	"testing"
+
+	"github.com/Entrio/aeonofstrife/fov"
+)
+
+type testVisionMap struct {
+	rows []string
+}
+
+func (m testVisionMap) Size() (int, int) {
+	return len(m.rows[0]), len(m.rows)
+}
+
+func (m testVisionMap) IsOpaque(x, y int) bool {
+	return m.rows[y][x] == '#'
+}
+
+var pillarRoom = testVisionMap{rows: []string{
+	"#########",
+	"#.......#",
+	"#.......#",
+	"#...#...#",
+	"#.......#",
+	"#.......#",
+	"#########",
+}}
+
+func TestComputeHidesTilesBehindWalls(t *testing.T) {
+	v := fov.Compute(pillarRoom, fov.Point{X: 4, Y: 5}, 10)
+
+	if !v.Visible(4, 3) {
+		t.Fatalf("Expected the pillar itself to be visible")
+	}
+	if v.Visible(4, 1) {
+		t.Fatalf("Expected the tile behind the pillar to be hidden")
+	}
+	if !v.Visible(1, 1) || !v.Visible(7, 1) {
+		t.Fatalf("Expected the far corners to be visible")
+	}
+}
+
+func TestComputeRespectsRadius(t *testing.T) {
+	v := fov.Compute(pillarRoom, fov.Point{X: 1, Y: 1}, 2)
+
+	if !v.Visible(3, 1) {
+		t.Fatalf("Expected a tile within the radius to be visible")
+	}
+	if v.Visible(7, 1) {
+		t.Fatalf("Expected a tile outside of the radius to be hidden")
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	if fov.LineOfSight(pillarRoom, fov.Point{X: 4, Y: 5}, fov.Point{X: 4, Y: 1}) {
+		t.Fatalf("Expected the pillar to block line of sight")
+	}
+	if !fov.LineOfSight(pillarRoom, fov.Point{X: 1, Y: 1}, fov.Point{X: 7, Y: 2}) {
+		t.Fatalf("Expected a clear line of sight past the pillar")
+	}
+}
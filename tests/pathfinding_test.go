@@ -0,0 +1,157 @@
+package copy_test
+
+import (
+	"testing"
+
+	"github.com/Entrio/aeonofstrife/pathfinding"
+)
+
+type testGrid struct {
+	rows []string
+}
+
+func (g testGrid) Size() (int, int) {
+	return len(g.rows[0]), len(g.rows)
+}
+
+func (g testGrid) IsPassable(x, y int) bool {
+	return g.rows[y][x] != '#'
+}
+
+type testGraph map[string][]string
+
+func (g testGraph) Destinations(roomID string) []string {
+	return g[roomID]
+}
+
+var maze = testGrid{rows: []string{
+	"#######",
+	"#.....#",
+	"#.###.#",
+	"#.#...#",
+	"#.#.###",
+	"#...#.#",
+	"#######",
+}}
+
+func TestFindPathAroundWalls(t *testing.T) {
+	path, ok := pathfinding.FindPath(maze, pathfinding.Point{X: 1, Y: 1}, pathfinding.Point{X: 3, Y: 3}, pathfinding.Options{})
+	if !ok {
+		t.Fatalf("Expected a path to be found")
+	}
+	if len(path) != 8 {
+		t.Fatalf("Expected path length to be 8 but was %d", len(path))
+	}
+	if last := path[len(path)-1]; last.X != 3 || last.Y != 3 {
+		t.Fatalf("Expected path to end at the goal but ended at %v", last)
+	}
+}
+
+func TestFindPathUnreachable(t *testing.T) {
+	if _, ok := pathfinding.FindPath(maze, pathfinding.Point{X: 1, Y: 1}, pathfinding.Point{X: 5, Y: 5}, pathfinding.Options{}); ok {
+		t.Fatalf("Expected the enclosed tile to be unreachable")
+	}
+}
+
+func TestReachable(t *testing.T) {
+	if !pathfinding.Reachable(maze, pathfinding.Point{X: 1, Y: 1}, pathfinding.Point{X: 3, Y: 3}, pathfinding.Options{}) {
+		t.Fatalf("Expected the corridor to connect both tiles")
+	}
+	if pathfinding.Reachable(maze, pathfinding.Point{X: 1, Y: 1}, pathfinding.Point{X: 5, Y: 5}, pathfinding.Options{Diagonal: true}) {
+		t.Fatalf("Expected the enclosed tile to be unreachable even with diagonal movement")
+	}
+}
+
+func TestFindPathDiagonal(t *testing.T) {
+	open := testGrid{rows: []string{".....", ".....", ".....", ".....", "....."}}
+	path, ok := pathfinding.FindPath(open, pathfinding.Point{X: 0, Y: 0}, pathfinding.Point{X: 4, Y: 4}, pathfinding.Options{Diagonal: true})
+	if !ok || len(path) != 4 {
+		t.Fatalf("Expected a diagonal path of 4 steps but got %d (found: %t)", len(path), ok)
+	}
+}
+
+type weightedGrid struct {
+	testGrid
+}
+
+func (g weightedGrid) MovementCost(x, y int) int {
+	if g.rows[y][x] == '~' {
+		return 5
+	}
+	return 1
+}
+
+func TestFindPathAvoidsSlowTiles(t *testing.T) {
+	grid := weightedGrid{testGrid{rows: []string{
+		".....",
+		".~~~.",
+		".....",
+	}}}
+	path, ok := pathfinding.FindPath(grid, pathfinding.Point{X: 0, Y: 1}, pathfinding.Point{X: 4, Y: 1}, pathfinding.Options{})
+	if !ok {
+		t.Fatalf("Expected a path to be found")
+	}
+	for _, p := range path {
+		if grid.rows[p.Y][p.X] == '~' {
+			t.Fatalf("Expected the path to go around the water but it crossed %v", p)
+		}
+	}
+	if len(path) != 6 {
+		t.Fatalf("Expected path length to be 6 but was %d", len(path))
+	}
+}
+
+func TestFindPathBlocked(t *testing.T) {
+	blocked := func(x, y int) bool { return (x == 1 && y == 2) || (x == 2 && y == 1) }
+	path, ok := pathfinding.FindPath(maze, pathfinding.Point{X: 1, Y: 1}, pathfinding.Point{X: 1, Y: 5}, pathfinding.Options{Blocked: blocked})
+	if ok {
+		t.Fatalf("Expected both corridors to be blocked but got %v", path)
+	}
+}
+
+func TestCacheRespectsObstacles(t *testing.T) {
+	cache := pathfinding.NewCache()
+	from, to := pathfinding.Point{X: 1, Y: 1}, pathfinding.Point{X: 5, Y: 1}
+
+	direct, ok := cache.FindPath(maze, 1, from, to, pathfinding.Options{})
+	if !ok || len(direct) != 4 {
+		t.Fatalf("Expected a direct path of 4 steps but got %v", direct)
+	}
+
+	blocked := func(x, y int) bool { return x == 3 && y == 1 }
+	detour, ok := cache.FindPath(maze, 1, from, to, pathfinding.Options{Blocked: blocked})
+	if !ok {
+		t.Fatalf("Expected a detour to be found")
+	}
+	for _, p := range detour {
+		if blocked(p.X, p.Y) {
+			t.Fatalf("Expected the cached path to be rejected because of the obstacle but got %v", detour)
+		}
+	}
+}
+
+func TestCacheStaysBounded(t *testing.T) {
+	cache := pathfinding.NewCache()
+	open := testGrid{rows: []string{"................................................"}}
+	from := pathfinding.Point{X: 0, Y: 0}
+	for i := 0; i < pathfinding.MaxCachedPaths*2; i++ {
+		// A target that keeps moving back and forth, like a chased player
+		to := pathfinding.Point{X: 1 + i%47, Y: 0}
+		from = pathfinding.Point{X: i % 40, Y: 0}
+		cache.FindPath(open, 1, from, to, pathfinding.Options{})
+		if cache.Len() > pathfinding.MaxCachedPaths {
+			t.Fatalf("Expected at most %d cached paths but there are %d", pathfinding.MaxCachedPaths, cache.Len())
+		}
+	}
+}
+
+func TestFindRoute(t *testing.T) {
+	graph := testGraph{"a": {"b"}, "b": {"c", "d"}, "d": {"e"}}
+	route, ok := pathfinding.FindRoute(graph, "a", "e")
+	if !ok || len(route) != 4 || route[3] != "e" {
+		t.Fatalf("Expected route a -> b -> d -> e but got %v", route)
+	}
+	if _, ok := pathfinding.FindRoute(graph, "e", "a"); ok {
+		t.Fatalf("Expected exits to be one way")
+	}
+}
@@ -0,0 +1,170 @@
+package copy_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/Entrio/aeonofstrife/game"
+)
+
+// walledRoom builds a room like the generator does, walls around a dirt floor with a few pillars
+func walledRoom(width, height int) *game.Room {
+	room := game.NewRoom(width, height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			tile := game.Tile{Type: game.TILE_TYPE_DIRT, IsPassable: true, Position: game.Vector2{X: x, Y: y}}
+			if x == 0 || y == 0 || x == width-1 || y == height-1 || (x%16 == 8 && y%16 == 8) {
+				tile.Type, tile.IsPassable = game.TILE_TYPE_WALL, false
+			}
+			room.Tiles[x][y] = tile
+		}
+	}
+	return room
+}
+
+// decodeRoomV2 reads the tiles back out of a v2 room the way a client would
+func decodeRoomV2(t *testing.T, data []byte) (width, height int, tiles []game.Tile) {
+	r := bytes.NewReader(data)
+	readString := func() {
+		var n uint16
+		binary.Read(r, binary.LittleEndian, &n)
+		r.Seek(int64(n), 1)
+	}
+	readString()
+	readString()
+	readString()
+
+	w, _ := r.ReadByte()
+	h, _ := r.ReadByte()
+	width, height = int(w), int(h)
+
+	paletteSize, _ := r.ReadByte()
+	palette := make([]byte, paletteSize)
+	r.Read(palette)
+
+	var runs uint16
+	binary.Read(r, binary.LittleEndian, &runs)
+	types := make([]uint8, 0, width*height)
+	for i := 0; i < int(runs); i++ {
+		index, _ := r.ReadByte()
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatalf("Failed to read run length: %v", err)
+		}
+		for j := 0; j < int(length); j++ {
+			types = append(types, palette[index])
+		}
+	}
+	if len(types) != width*height {
+		t.Fatalf("Expected runs to cover %d tiles, got %d", width*height, len(types))
+	}
+
+	passable := make([]byte, (width*height+7)/8)
+	r.Read(passable)
+	for i, tileType := range types {
+		tiles = append(tiles, game.Tile{
+			Type:       tileType,
+			IsPassable: passable[i/8]&(1<<(i%8)) != 0,
+			Position:   game.Vector2{X: i % width, Y: i / width},
+		})
+	}
+	return width, height, tiles
+}
+
+func TestRoomDataV2RoundTrip(t *testing.T) {
+	room := walledRoom(40, 30)
+	room.Tiles[5][7] = game.Tile{Type: game.TILE_TYPE_PORTAL, IsPassable: true, Position: game.Vector2{X: 5, Y: 7}}
+
+	packet := game.NewPacket(game.MsgRoomCountResponse)
+	packet.WriteRoomDataV2(room)
+
+	width, height, tiles := decodeRoomV2(t, packet.GetFrame()[2:])
+	if width != 40 || height != 30 {
+		t.Fatalf("Expected a 40x30 room, got %dx%d", width, height)
+	}
+	for _, tile := range tiles {
+		original := room.Tiles[tile.Position.X][tile.Position.Y]
+		if tile.Type != original.Type || tile.IsPassable != original.IsPassable {
+			t.Fatalf("Tile %v decoded as %+v, expected %+v", tile.Position, tile, original)
+		}
+	}
+}
+
+func TestRoomDataV2IsSmaller(t *testing.T) {
+	room := walledRoom(255, 255)
+
+	v1 := game.NewPacket(game.MsgRoomCountResponse)
+	v1.WriteRoomData(room)
+	v2 := game.NewPacket(game.MsgRoomCountResponse)
+	v2.WriteRoomDataV2(room)
+
+	if len(v2.GetFrame())*4 > len(v1.GetFrame()) {
+		t.Fatalf("Expected v2 to be at most a quarter of v1, got %d bytes against %d", len(v2.GetFrame()), len(v1.GetFrame()))
+	}
+}
+
+func BenchmarkWriteRoomDataV1(b *testing.B) {
+	room := walledRoom(255, 255)
+	b.ReportAllocs()
+	size := 0
+	for i := 0; i < b.N; i++ {
+		packet := game.NewPacket(game.MsgRoomCountResponse)
+		packet.WriteRoomData(room)
+		size = len(packet.GetFrame())
+	}
+	b.ReportMetric(float64(size), "bytes/room")
+}
+
+func BenchmarkWriteRoomDataV2(b *testing.B) {
+	room := walledRoom(255, 255)
+	b.ReportAllocs()
+	size := 0
+	for i := 0; i < b.N; i++ {
+		packet := game.NewPacket(game.MsgRoomCountResponse)
+		packet.WriteRoomDataV2(room)
+		size = len(packet.GetFrame())
+	}
+	b.ReportMetric(float64(size), "bytes/room")
+}
+
+func TestRoomChunkCoversEdgeOfLargeRoom(t *testing.T) {
+	room := walledRoom(300, 70)
+	room.Tiles[299][69] = game.Tile{Type: game.TILE_TYPE_PORTAL, IsPassable: true, Position: game.Vector2{X: 299, Y: 69}}
+
+	// 300 = 9 * 32 + 12 and 70 = 2 * 32 + 6, the last chunk is 12x6
+	packet := game.NewPacket(game.MsgRoomChunk)
+	packet.WriteRoomChunk(room, 9, 2)
+
+	r := bytes.NewReader(packet.GetFrame()[2:])
+	var idLength, cx, cy uint16
+	binary.Read(r, binary.LittleEndian, &idLength)
+	r.Seek(int64(idLength), 1)
+	binary.Read(r, binary.LittleEndian, &cx)
+	binary.Read(r, binary.LittleEndian, &cy)
+	w, _ := r.ReadByte()
+	h, _ := r.ReadByte()
+	if cx != 9 || cy != 2 || w != 12 || h != 6 {
+		t.Fatalf("Expected chunk 9,2 of 12x6, got chunk %d,%d of %dx%d", cx, cy, w, h)
+	}
+
+	paletteSize, _ := r.ReadByte()
+	palette := make([]byte, paletteSize)
+	r.Read(palette)
+	var runs uint16
+	binary.Read(r, binary.LittleEndian, &runs)
+	types := make([]uint8, 0, int(w)*int(h))
+	for i := 0; i < int(runs); i++ {
+		index, _ := r.ReadByte()
+		length, _ := binary.ReadUvarint(r)
+		for j := 0; j < int(length); j++ {
+			types = append(types, palette[index])
+		}
+	}
+	if len(types) != int(w)*int(h) {
+		t.Fatalf("Expected runs to cover %d tiles, got %d", int(w)*int(h), len(types))
+	}
+	if types[len(types)-1] != game.TILE_TYPE_PORTAL {
+		t.Fatalf("Expected the last tile of the chunk to be the corner of the room")
+	}
+}
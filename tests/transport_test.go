@@ -0,0 +1,172 @@
+package copy_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Entrio/aeonofstrife/game"
+)
+
+func lengthPrefixed(frame []byte) []byte {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, uint32(len(frame)))
+	return append(data, frame...)
+}
+
+func TestPipeTransportSplitsCoalescedFrames(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	defer transport.Close()
+
+	first := []byte{100, 0, 'a', 'b'}
+	second := []byte{102, 0, 3}
+	go func() {
+		// Both frames in a single write, the way TCP tends to deliver them
+		client.Write(append(lengthPrefixed(first), lengthPrefixed(second)...))
+	}()
+
+	for _, expected := range [][]byte{first, second} {
+		frame, err := transport.ReadFrame()
+		if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		if !bytes.Equal(frame, expected) {
+			t.Fatalf("Expected frame %v, got %v", expected, frame)
+		}
+	}
+}
+
+func TestPipeTransportJoinsFragmentedFrames(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	defer transport.Close()
+
+	frame := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	data := lengthPrefixed(frame)
+	go func() {
+		for _, b := range data {
+			client.Write([]byte{b})
+		}
+	}()
+
+	got, err := transport.ReadFrame()
+	if err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	if !bytes.Equal(got, frame) {
+		t.Fatalf("Expected frame %v, got %v", frame, got)
+	}
+}
+
+func TestPipeTransportWritesLengthPrefix(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	defer transport.Close()
+
+	frame := []byte{1, 0, 'h', 'i'}
+	go transport.WriteFrame(frame)
+
+	data := make([]byte, 4+len(frame))
+	if _, err := io.ReadFull(client, data); err != nil {
+		t.Fatalf("Failed to read from pipe: %v", err)
+	}
+	if !bytes.Equal(data, lengthPrefixed(frame)) {
+		t.Fatalf("Expected %v on the wire, got %v", lengthPrefixed(frame), data)
+	}
+}
+
+func TestPipeTransportRejectsOversizedFrames(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	defer transport.Close()
+
+	go client.Write([]byte{0xff, 0xff, 0xff, 0xff})
+
+	if _, err := transport.ReadFrame(); err != game.ErrFrameTooLarge {
+		t.Fatalf("Expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestPipeTransportReportsClosedConnections(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	client.Close()
+
+	if _, err := transport.ReadFrame(); err == nil {
+		t.Fatalf("Expected an error after the client went away")
+	}
+}
+
+func TestPipeTransportCompressesLargeFrames(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	defer transport.Close()
+	transport.(game.CompressingTransport).EnableCompression(64)
+
+	// Mostly walls, like a freshly generated room
+	frame := append([]byte{13, 0}, bytes.Repeat([]byte{0, 0, 1, 1}, 4096)...)
+	go transport.WriteFrame(frame)
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(client, header); err != nil {
+		t.Fatalf("Failed to read from pipe: %v", err)
+	}
+	length := binary.LittleEndian.Uint32(header)
+	if length&(1<<31) == 0 {
+		t.Fatalf("Expected the compressed flag to be set")
+	}
+	length &^= 1 << 31
+	if int(length) >= len(frame)/10 {
+		t.Fatalf("Expected the frame to shrink a lot, %d bytes became %d", len(frame), length)
+	}
+
+	compressed := make([]byte, length)
+	if _, err := io.ReadFull(client, compressed); err != nil {
+		t.Fatalf("Failed to read from pipe: %v", err)
+	}
+	inflated, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatalf("Failed to inflate frame: %v", err)
+	}
+	if !bytes.Equal(inflated, frame) {
+		t.Fatalf("Expected the inflated frame to match the original")
+	}
+}
+
+func TestPipeTransportLeavesSmallFramesAlone(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	defer transport.Close()
+	transport.(game.CompressingTransport).EnableCompression(64)
+
+	frame := []byte{1, 0, 'h', 'i'}
+	go transport.WriteFrame(frame)
+
+	data := make([]byte, 4+len(frame))
+	if _, err := io.ReadFull(client, data); err != nil {
+		t.Fatalf("Failed to read from pipe: %v", err)
+	}
+	if !bytes.Equal(data, lengthPrefixed(frame)) {
+		t.Fatalf("Expected %v on the wire, got %v", lengthPrefixed(frame), data)
+	}
+}
+
+func TestPipeTransportInflatesCompressedFrames(t *testing.T) {
+	transport, client := game.NewPipeTransport()
+	defer transport.Close()
+
+	frame := append([]byte{100, 0}, bytes.Repeat([]byte("abc"), 100)...)
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write(frame)
+	w.Close()
+
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, uint32(buf.Len())|1<<31)
+	go client.Write(append(data, buf.Bytes()...))
+
+	got, err := transport.ReadFrame()
+	if err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	if !bytes.Equal(got, frame) {
+		t.Fatalf("Expected the inflated frame to match the original")
+	}
+}